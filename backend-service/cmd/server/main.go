@@ -23,6 +23,11 @@ import (
 	appmiddleware "code-type/backend/internal/http/middleware"
 	"code-type/backend/internal/kratos"
 	"code-type/backend/internal/services/account"
+	"code-type/backend/internal/services/achievements"
+	"code-type/backend/internal/services/challenges"
+	"code-type/backend/internal/services/drills"
+	"code-type/backend/internal/services/leaderboards"
+	"code-type/backend/internal/services/races"
 	"code-type/backend/internal/storage"
 )
 
@@ -41,11 +46,61 @@ func main() {
 	}
 	defer db.Close()
 
+	// rootCtx scopes background workers; it is cancelled once the HTTP server has shut down.
+	rootCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+
 	historyRepo := storage.NewHistoryRepository(db)
-	historyHandler := handlers.NewHistoryHandler(historyRepo)
+	snippetRepo := storage.NewSnippetRepository(db)
+	profileRepo := storage.NewProfileRepository(db)
+	leaderboardRepo := storage.NewLeaderboardRepository(db)
+	goalRepo := storage.NewGoalRepository(db)
+	achievementRepo := storage.NewAchievementRepository(db)
+	preferencesRepo := storage.NewPreferencesRepository(db)
+	raceRepo := storage.NewRaceRepository(db)
+	teamRepo := storage.NewTeamRepository(db)
+	challengeRepo := storage.NewChallengeRepository(db)
+	accountDeletionRepo := storage.NewAccountDeletionRepository(db)
+
+	// Rooms of a previous process with this instance ID are gone; close their races before serving.
+	// Races of other replicas are left to their lease (see races.Service.RunLeases).
+	if err := raceRepo.ExpireInstance(ctx, cfg.InstanceID); err != nil {
+		log.Fatalf("failed to expire unfinished races: %v", err)
+	}
+
+	achievementRules, err := achievements.LoadRules(cfg.AchievementsConfig)
+	if err != nil {
+		log.Fatalf("failed to load achievements: %v", err)
+	}
+	achievementEngine := achievements.NewEngine(achievementRules, achievementRepo, goalRepo)
+	historyRepo.OnCreate(achievementEngine.Notify)
+
+	historyHandler := handlers.NewHistoryHandler(historyRepo, snippetRepo)
+	snippetHandler := handlers.NewSnippetHandler(snippetRepo)
+	statsHandler := handlers.NewStatsHandler(historyRepo)
+	analyticsRepo := storage.NewAnalyticsRepository(db)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsRepo)
+	drillsHandler := handlers.NewDrillsHandler(drills.NewService(analyticsRepo, snippetRepo))
+	profileHandler := handlers.NewProfileHandler(profileRepo)
+	leaderboardHandler := handlers.NewLeaderboardHandler(leaderboardRepo, snippetRepo)
+	goalsHandler := handlers.NewGoalsHandler(goalRepo)
+	achievementsHandler := handlers.NewAchievementsHandler(achievementEngine)
+	preferencesHandler := handlers.NewPreferencesHandler(preferencesRepo, snippetRepo)
+	racesService := races.NewService(rootCtx, raceRepo, snippetRepo, historyRepo, cfg.InstanceID, races.DefaultOptions)
+	racesHandler := handlers.NewRacesHandler(racesService, cfg.WebSocketOrigins)
+	ghostsHandler := handlers.NewGhostsHandler(historyRepo, snippetRepo)
+	teamsHandler := handlers.NewTeamsHandler(teamRepo, challengeRepo, snippetRepo)
 	kratosAdminClient := kratos.NewAdminClient(cfg.KratosAdminURL)
-	accountService := account.NewService(kratosAdminClient, historyRepo)
-	accountHandler := handlers.NewAccountHandler(accountService)
+	accountService := account.NewService(kratosAdminClient, accountDeletionRepo, cfg.AccountDeletionGracePeriod)
+	accountExporter := account.NewExporter(kratosAdminClient, account.ExportSources{
+		History:      historyRepo,
+		Profiles:     profileRepo,
+		Preferences:  preferencesRepo,
+		Goals:        goalRepo,
+		Achievements: achievementRepo,
+		Teams:        teamRepo,
+	})
+	accountHandler := handlers.NewAccountHandler(accountService, accountExporter)
 
 	router := chi.NewRouter()
 	router.Use(chimiddleware.RequestID)
@@ -57,12 +112,28 @@ func main() {
 	// Public routes are accessible without authentication.
 	// Private routes require X-User-Id header set by Oathkeeper after session validation.
 	router.Route("/api", func(r chi.Router) {
-		r.Route("/public", handlers.RegisterPublicRoutes)
+		r.Route("/public", func(pub chi.Router) {
+			handlers.RegisterPublicRoutes(pub, snippetHandler, leaderboardHandler, accountHandler)
+		})
 
 		r.Group(func(private chi.Router) {
 			private.Use(appmiddleware.AuthHeaderMiddleware)
 			private.Route("/private", func(pr chi.Router) {
-				handlers.RegisterPrivateRoutes(pr, historyHandler, accountHandler)
+				handlers.RegisterPrivateRoutes(pr, cfg.AdminUserIDs, handlers.PrivateHandlers{
+					History:      historyHandler,
+					Account:      accountHandler,
+					Snippets:     snippetHandler,
+					Stats:        statsHandler,
+					Analytics:    analyticsHandler,
+					Drills:       drillsHandler,
+					Profile:      profileHandler,
+					Goals:        goalsHandler,
+					Achievements: achievementsHandler,
+					Preferences:  preferencesHandler,
+					Races:        racesHandler,
+					Ghosts:       ghostsHandler,
+					Teams:        teamsHandler,
+				})
 			})
 		})
 	})
@@ -73,6 +144,12 @@ func main() {
 		ReadHeaderTimeout: 5 * time.Second, // Prevent DDoS attacks
 	}
 
+	go leaderboards.NewRefresher(leaderboardRepo, cfg.LeaderboardRefreshInterval).Run(rootCtx)
+	go achievementEngine.Run(rootCtx)
+	go challenges.NewScheduler(challengeRepo, cfg.ChallengeScheduleInterval).Run(rootCtx)
+	go racesService.RunLeases(rootCtx)
+	go account.NewDeletionWorker(accountService, cfg.AccountDeletionRetryInterval).Run(rootCtx)
+
 	// Start server in goroutine to allow graceful shutdown handling
 	go func() {
 		log.Printf("HTTP server listening on %s", server.Addr)
@@ -82,6 +159,7 @@ func main() {
 	}()
 
 	waitForShutdown(server)
+	stop()
 }
 
 // waitForShutdown handles graceful shutdown on SIGINT or SIGTERM signals.
@@ -7,14 +7,25 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 )
 
 // Config holds application configuration loaded from environment variables.
 type Config struct {
-	HTTPPort        string // Server listening port
-	KratosPublicURL string // Kratos public API endpoint (via Oathkeeper proxy)
-	KratosAdminURL  string // Kratos admin API endpoint (direct)
-	DatabaseDSN     string // PostgreSQL connection string
+	HTTPPort        string   // Server listening port
+	KratosPublicURL string   // Kratos public API endpoint (via Oathkeeper proxy)
+	KratosAdminURL  string   // Kratos admin API endpoint (direct)
+	DatabaseDSN     string   // PostgreSQL connection string
+	AdminUserIDs    []string // Kratos identity IDs allowed to manage the snippet catalog
+	InstanceID      string   // Names this replica; must be unique among replicas and stable across its restarts
+
+	LeaderboardRefreshInterval   time.Duration // How often leaderboards are recomputed in the background
+	ChallengeScheduleInterval    time.Duration // How often team challenges are opened and closed
+	AccountDeletionRetryInterval time.Duration // How often failed account deletions are retried
+	AccountDeletionGracePeriod   time.Duration // How long a deleted account stays deactivated and restorable before it is purged
+	AchievementsConfig           string        // Optional path to achievement rules; built-in rules are used when empty
+	WebSocketOrigins             []string      // Browser origins allowed to open race sockets; same-host only when empty
 }
 
 // Load reads environment variables and validates required configuration.
@@ -25,7 +36,44 @@ func Load() (Config, error) {
 		KratosPublicURL: os.Getenv("KRATOS_PUBLIC_URL"),
 		KratosAdminURL:  os.Getenv("KRATOS_ADMIN_URL"),
 		DatabaseDSN:     os.Getenv("DATABASE_DSN"),
+		AdminUserIDs:    splitList(os.Getenv("ADMIN_USER_IDS")),
+	}
+
+	cfg.InstanceID = os.Getenv("INSTANCE_ID")
+	if cfg.InstanceID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return Config{}, fmt.Errorf("INSTANCE_ID is required when the hostname is unavailable: %w", err)
+		}
+		cfg.InstanceID = hostname
+	}
+
+	refreshInterval, err := time.ParseDuration(getEnvOrDefault("LEADERBOARD_REFRESH_INTERVAL", "1m"))
+	if err != nil || refreshInterval <= 0 {
+		return Config{}, fmt.Errorf("LEADERBOARD_REFRESH_INTERVAL must be a positive duration")
+	}
+	cfg.LeaderboardRefreshInterval = refreshInterval
+
+	scheduleInterval, err := time.ParseDuration(getEnvOrDefault("CHALLENGE_SCHEDULE_INTERVAL", "30s"))
+	if err != nil || scheduleInterval <= 0 {
+		return Config{}, fmt.Errorf("CHALLENGE_SCHEDULE_INTERVAL must be a positive duration")
+	}
+	cfg.ChallengeScheduleInterval = scheduleInterval
+
+	deletionRetryInterval, err := time.ParseDuration(getEnvOrDefault("ACCOUNT_DELETION_RETRY_INTERVAL", "1m"))
+	if err != nil || deletionRetryInterval <= 0 {
+		return Config{}, fmt.Errorf("ACCOUNT_DELETION_RETRY_INTERVAL must be a positive duration")
+	}
+	cfg.AccountDeletionRetryInterval = deletionRetryInterval
+
+	deletionGracePeriod, err := time.ParseDuration(getEnvOrDefault("ACCOUNT_DELETION_GRACE_PERIOD", "168h"))
+	if err != nil || deletionGracePeriod < 0 {
+		return Config{}, fmt.Errorf("ACCOUNT_DELETION_GRACE_PERIOD must be a non-negative duration")
 	}
+	cfg.AccountDeletionGracePeriod = deletionGracePeriod
+
+	cfg.AchievementsConfig = os.Getenv("ACHIEVEMENTS_CONFIG")
+	cfg.WebSocketOrigins = splitList(os.Getenv("WS_ALLOWED_ORIGINS"))
 
 	if cfg.KratosPublicURL == "" {
 		return Config{}, fmt.Errorf("KRATOS_PUBLIC_URL is required")
@@ -50,3 +98,15 @@ func getEnvOrDefault(key, def string) string {
 
 	return def
 }
+
+// splitList parses a comma-separated environment value, dropping empty items.
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
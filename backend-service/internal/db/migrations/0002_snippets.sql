@@ -0,0 +1,148 @@
+CREATE TABLE IF NOT EXISTS snippets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    language TEXT NOT NULL,
+    title TEXT NOT NULL,
+    difficulty TEXT NOT NULL CHECK (difficulty IN ('easy', 'medium', 'hard')),
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    content TEXT NOT NULL CHECK (content <> ''),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_snippets_language_difficulty
+    ON snippets (language, difficulty);
+
+CREATE INDEX IF NOT EXISTS idx_snippets_tags
+    ON snippets USING GIN (tags);
+
+-- Seed the catalog with the snippets previously bundled with the frontend.
+INSERT INTO snippets (language, title, difficulty, tags, content) VALUES
+    ('javascript', 'Greeting function', 'easy', ARRAY['functions', 'template-literals', 'objects'], $snippet$function greet(name) {
+  return `Hello, ${name}!`;
+}
+
+const user = {
+  firstName: "John",
+  lastName: "Doe",
+};
+
+console.log(greet(user.firstName));$snippet$),
+    ('javascript', 'Debounce helper', 'medium', ARRAY['closures', 'arrow-functions', 'timers'], $snippet$const debounce = (func, delay) => {
+  let timeoutId;
+
+  return (...args) => {
+    clearTimeout(timeoutId);
+    timeoutId = setTimeout(() => {
+      func.apply(null, args);
+    }, delay);
+  };
+};$snippet$),
+    ('javascript', 'Event emitter', 'hard', ARRAY['classes', 'arrays', 'callbacks'], $snippet$class EventEmitter {
+  constructor() {
+    this.events = {};
+  }
+
+  on(event, listener) {
+    if (!this.events[event]) {
+      this.events[event] = [];
+    }
+
+    this.events[event].push(listener);
+  }
+
+  emit(event, data) {
+    if (this.events[event]) {
+      this.events[event].forEach((listener) => listener(data));
+    }
+  }
+}$snippet$),
+    ('python', 'Fibonacci sequence', 'easy', ARRAY['functions', 'lists', 'loops'], $snippet$def fibonacci(n):
+    sequence = [0, 1]
+    while len(sequence) < n:
+        sequence.append(sequence[-1] + sequence[-2])
+    return sequence
+
+
+print(fibonacci(10))$snippet$),
+    ('python', 'Flatten nested list', 'medium', ARRAY['recursion', 'lists'], $snippet$def flatten_list(nested_list):
+    result = []
+    for item in nested_list:
+        if isinstance(item, list):
+            result.extend(flatten_list(item))
+        else:
+            result.append(item)
+    return result$snippet$),
+    ('python', 'Stack class', 'medium', ARRAY['classes', 'lists'], $snippet$class Stack:
+    def __init__(self):
+        self.items = []
+
+    def push(self, item):
+        self.items.append(item)
+
+    def pop(self):
+        if not self.is_empty():
+            return self.items.pop()
+        return None
+
+    def is_empty(self):
+        return len(self.items) == 0$snippet$),
+    ('go', 'Sum of slice', 'easy', ARRAY['loops', 'slices'], $snippet$package main
+
+import "fmt"
+
+func main() {
+	nums := []int{1, 2, 3, 4, 5}
+	sum := 0
+	for _, num := range nums {
+		sum += num
+	}
+	fmt.Println("Sum:", sum)
+}$snippet$),
+    ('go', 'Fibonacci sequence', 'medium', ARRAY['functions', 'slices', 'loops'], $snippet$package main
+
+import "fmt"
+
+func Fibonacci(n int) []int {
+	sequence := []int{0, 1}
+	for len(sequence) < n {
+		next := sequence[len(sequence)-1] + sequence[len(sequence)-2]
+		sequence = append(sequence, next)
+	}
+	return sequence
+}
+
+func main() {
+	fmt.Println(Fibonacci(10))
+}$snippet$),
+    ('go', 'Worker pool', 'hard', ARRAY['goroutines', 'channels', 'concurrency'], $snippet$package main
+
+import (
+	"fmt"
+	"time"
+)
+
+func worker(id int, jobs <-chan int, results chan<- int) {
+	for job := range jobs {
+		fmt.Printf("worker %d processing job %d", id, job)
+		time.Sleep(100 * time.Millisecond)
+		results <- job * 2
+	}
+}
+
+func main() {
+	jobs := make(chan int, 5)
+	results := make(chan int, 5)
+
+	for w := 1; w <= 3; w++ {
+		go worker(w, jobs, results)
+	}
+
+	for j := 1; j <= 5; j++ {
+		jobs <- j
+	}
+	close(jobs)
+
+	for a := 1; a <= 5; a++ {
+		fmt.Println(<-results)
+	}
+}$snippet$);
@@ -0,0 +1,11 @@
+ALTER TABLE practice_history
+    ADD COLUMN IF NOT EXISTS snippet_id UUID REFERENCES snippets (id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS snippet_hash TEXT CHECK (snippet_hash ~ '^[0-9a-f]{64}$');
+
+CREATE INDEX IF NOT EXISTS idx_practice_history_user_snippet_id
+    ON practice_history (user_id, snippet_id, wpm DESC)
+    WHERE snippet_id IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_practice_history_user_snippet_hash
+    ON practice_history (user_id, snippet_hash, wpm DESC)
+    WHERE snippet_hash IS NOT NULL;
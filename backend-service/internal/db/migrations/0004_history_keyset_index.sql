@@ -0,0 +1,5 @@
+-- Include id as a tie-breaker so keyset pagination on (completed_at, id) is served by the index.
+DROP INDEX IF EXISTS idx_practice_history_user_completed_at;
+
+CREATE INDEX IF NOT EXISTS idx_practice_history_user_completed_at
+    ON practice_history (user_id, completed_at DESC, id DESC);
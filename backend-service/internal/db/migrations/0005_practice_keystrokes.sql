@@ -0,0 +1,12 @@
+-- Keystroke logs are stored compressed, one row per history entry.
+CREATE TABLE IF NOT EXISTS practice_keystrokes (
+    history_id UUID PRIMARY KEY REFERENCES practice_history (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    encoding TEXT NOT NULL,
+    keystroke_count INTEGER NOT NULL CHECK (keystroke_count >= 0),
+    data BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_practice_keystrokes_user_id
+    ON practice_keystrokes (user_id);
@@ -0,0 +1,4 @@
+-- Runs are verified server-side when a keystroke log or snippet reference makes it possible.
+ALTER TABLE practice_history
+    ADD COLUMN IF NOT EXISTS verification_status TEXT NOT NULL DEFAULT 'unverified'
+        CHECK (verification_status IN ('unverified', 'verified', 'flagged'));
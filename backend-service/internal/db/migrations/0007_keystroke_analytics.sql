@@ -0,0 +1,29 @@
+-- Per-run key and bigram aggregates derived from keystroke logs.
+-- Rows follow their history entry, so deleting a run also removes its contribution to analytics.
+CREATE TABLE IF NOT EXISTS history_key_stats (
+    history_id UUID NOT NULL REFERENCES practice_history (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    language TEXT NOT NULL,
+    key TEXT NOT NULL,
+    presses INTEGER NOT NULL CHECK (presses >= 0),
+    errors INTEGER NOT NULL CHECK (errors >= 0),
+    latency_ms_total BIGINT NOT NULL CHECK (latency_ms_total >= 0),
+    latency_samples INTEGER NOT NULL CHECK (latency_samples >= 0),
+    PRIMARY KEY (history_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_history_key_stats_user_language
+    ON history_key_stats (user_id, language);
+
+CREATE TABLE IF NOT EXISTS history_bigram_stats (
+    history_id UUID NOT NULL REFERENCES practice_history (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    language TEXT NOT NULL,
+    bigram TEXT NOT NULL,
+    occurrences INTEGER NOT NULL CHECK (occurrences >= 0),
+    latency_ms_total BIGINT NOT NULL CHECK (latency_ms_total >= 0),
+    PRIMARY KEY (history_id, bigram)
+);
+
+CREATE INDEX IF NOT EXISTS idx_history_bigram_stats_user_language
+    ON history_bigram_stats (user_id, language);
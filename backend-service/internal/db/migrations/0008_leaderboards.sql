@@ -0,0 +1,46 @@
+CREATE TABLE IF NOT EXISTS user_profiles (
+    user_id UUID PRIMARY KEY,
+    display_name TEXT NOT NULL,
+    leaderboard_opt_in BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_profiles_display_name
+    ON user_profiles (lower(display_name));
+
+-- Best verified run per user for every board. Boards are keyed by period
+-- (daily and weekly windows in UTC, or all_time), scope (language or snippet)
+-- and the scope key (language name or snippet hash). Display names and the
+-- opt-in flag are joined at read time so profile changes apply immediately.
+CREATE MATERIALIZED VIEW IF NOT EXISTS leaderboard_entries AS
+WITH windows (period, since) AS (
+    VALUES
+        ('daily', date_trunc('day', NOW() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'),
+        ('weekly', date_trunc('week', NOW() AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'),
+        ('all_time', '-infinity'::timestamptz)
+),
+scoped AS (
+    SELECT w.period, 'language' AS scope, h.language AS scope_key,
+           h.user_id, h.id, h.language, h.wpm, h.accuracy, h.completed_at
+    FROM practice_history h
+    JOIN windows w ON h.completed_at >= w.since
+    WHERE h.verification_status = 'verified'
+    UNION ALL
+    SELECT w.period, 'snippet', h.snippet_hash,
+           h.user_id, h.id, h.language, h.wpm, h.accuracy, h.completed_at
+    FROM practice_history h
+    JOIN windows w ON h.completed_at >= w.since
+    WHERE h.verification_status = 'verified' AND h.snippet_hash IS NOT NULL
+)
+SELECT DISTINCT ON (period, scope, scope_key, user_id)
+       period, scope, scope_key, user_id, id AS history_id, language, wpm, accuracy, completed_at
+FROM scoped
+ORDER BY period, scope, scope_key, user_id, wpm DESC, accuracy DESC, completed_at ASC;
+
+-- Required by REFRESH MATERIALIZED VIEW CONCURRENTLY.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_leaderboard_entries_user
+    ON leaderboard_entries (period, scope, scope_key, user_id);
+
+CREATE INDEX IF NOT EXISTS idx_leaderboard_entries_rank
+    ON leaderboard_entries (period, scope, scope_key, wpm DESC, accuracy DESC, completed_at);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS user_goal_settings (
+    user_id UUID PRIMARY KEY,
+    time_zone TEXT NOT NULL DEFAULT 'UTC',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- An empty language means the goal counts runs in every language.
+CREATE TABLE IF NOT EXISTS user_goals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('daily_minutes', 'weekly_runs', 'target_wpm')),
+    language TEXT NOT NULL DEFAULT '',
+    target INTEGER NOT NULL CHECK (target > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, kind, language)
+);
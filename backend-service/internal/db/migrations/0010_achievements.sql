@@ -0,0 +1,8 @@
+-- achievement_id refers to a rule in the achievements config; history_id is the run that unlocked it.
+CREATE TABLE IF NOT EXISTS user_achievements (
+    user_id UUID NOT NULL,
+    achievement_id TEXT NOT NULL,
+    history_id UUID REFERENCES practice_history (id) ON DELETE SET NULL,
+    unlocked_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, achievement_id)
+);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS user_preferences (
+    user_id UUID PRIMARY KEY,
+    theme TEXT NOT NULL DEFAULT 'system' CHECK (theme IN ('system', 'light', 'dark')),
+    default_language TEXT NOT NULL DEFAULT '',
+    snippet_length TEXT NOT NULL DEFAULT 'any' CHECK (snippet_length IN ('any', 'short', 'medium', 'long')),
+    show_live_stats BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
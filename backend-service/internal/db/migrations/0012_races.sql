@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS races (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code TEXT NOT NULL UNIQUE,
+    owner_id UUID NOT NULL,
+    snippet_id UUID REFERENCES snippets (id) ON DELETE SET NULL,
+    snippet_hash TEXT NOT NULL CHECK (snippet_hash ~ '^[0-9a-f]{64}$'),
+    language TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'waiting'
+        CHECK (status IN ('waiting', 'countdown', 'running', 'finished', 'expired')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_races_owner ON races (owner_id);
+
+CREATE TABLE IF NOT EXISTS race_participants (
+    race_id UUID NOT NULL REFERENCES races (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (race_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_race_participants_user ON race_participants (user_id);
+
+ALTER TABLE practice_history
+    ADD COLUMN IF NOT EXISTS race_id UUID REFERENCES races (id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_practice_history_race
+    ON practice_history (race_id)
+    WHERE race_id IS NOT NULL;
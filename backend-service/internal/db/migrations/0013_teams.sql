@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS teams (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    invite_code TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Members are addressed by their membership id so teammates never see identity IDs.
+CREATE TABLE IF NOT EXISTS team_members (
+    id UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    team_id UUID NOT NULL REFERENCES teams (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (team_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_members_user ON team_members (user_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_team_members_owner
+    ON team_members (team_id)
+    WHERE role = 'owner';
@@ -0,0 +1,46 @@
+-- Challenges move from scheduled to open to closed; the scheduler performs both transitions
+-- and writes the final standings in the same transaction as the close.
+CREATE TABLE IF NOT EXISTS team_challenges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    team_id UUID NOT NULL REFERENCES teams (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    starts_at TIMESTAMPTZ NOT NULL,
+    ends_at TIMESTAMPTZ NOT NULL,
+    status TEXT NOT NULL DEFAULT 'scheduled' CHECK (status IN ('scheduled', 'open', 'closed')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    opened_at TIMESTAMPTZ,
+    closed_at TIMESTAMPTZ,
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_challenges_team ON team_challenges (team_id, starts_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_team_challenges_pending
+    ON team_challenges (ends_at)
+    WHERE status <> 'closed';
+
+-- Runs count toward a challenge by the hash of the typed text, like the snippet leaderboards.
+CREATE TABLE IF NOT EXISTS team_challenge_snippets (
+    challenge_id UUID NOT NULL REFERENCES team_challenges (id) ON DELETE CASCADE,
+    snippet_id UUID REFERENCES snippets (id) ON DELETE SET NULL,
+    snippet_hash TEXT NOT NULL CHECK (snippet_hash ~ '^[0-9a-f]{64}$'),
+    language TEXT NOT NULL,
+    PRIMARY KEY (challenge_id, snippet_hash)
+);
+
+-- Final standings are a snapshot: display names are copied so later profile or membership
+-- changes do not rewrite a closed challenge.
+CREATE TABLE IF NOT EXISTS team_challenge_standings (
+    challenge_id UUID NOT NULL REFERENCES team_challenges (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    member_id UUID NOT NULL,
+    display_name TEXT NOT NULL,
+    rank INTEGER NOT NULL,
+    score INTEGER NOT NULL,
+    snippets_completed INTEGER NOT NULL,
+    average_accuracy DOUBLE PRECISION NOT NULL,
+    last_completed_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (challenge_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_challenge_standings_user ON team_challenge_standings (user_id);
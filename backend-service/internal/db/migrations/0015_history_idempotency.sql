@@ -0,0 +1,13 @@
+-- Idempotency keys let clients retry POST /history without recording the run twice.
+-- request_hash tells a replay of the same run apart from a reused key.
+CREATE TABLE IF NOT EXISTS history_idempotency_keys (
+    user_id UUID NOT NULL,
+    idempotency_key TEXT NOT NULL,
+    request_hash TEXT NOT NULL,
+    history_id UUID NOT NULL REFERENCES practice_history (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_history_idempotency_keys_history
+    ON history_idempotency_keys (history_id);
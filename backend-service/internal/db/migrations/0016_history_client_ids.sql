@@ -0,0 +1,7 @@
+-- Runs recorded offline carry a client-generated ID so a batch can be synced more than once safely.
+ALTER TABLE practice_history
+    ADD COLUMN IF NOT EXISTS client_id UUID;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_practice_history_user_client_id
+    ON practice_history (user_id, client_id)
+    WHERE client_id IS NOT NULL;
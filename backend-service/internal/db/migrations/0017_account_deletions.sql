@@ -0,0 +1,34 @@
+-- Account deletion is a resumable workflow: the Kratos identity is deleted first, then every
+-- per-user table is purged in one transaction. Failed steps are retried from next_attempt_at.
+CREATE TABLE IF NOT EXISTS account_deletions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    identity_deleted_at TIMESTAMPTZ,
+    completed_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_deletions_user_pending
+    ON account_deletions (user_id)
+    WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_account_deletions_due
+    ON account_deletions (next_attempt_at)
+    WHERE status = 'pending';
+
+-- The audit trail outlives the purged data: it records who was deleted, when, and any failed steps.
+CREATE TABLE IF NOT EXISTS account_deletion_audit (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    deletion_id UUID NOT NULL REFERENCES account_deletions (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    event TEXT NOT NULL CHECK (event IN ('requested', 'identity_deleted', 'failed', 'completed')),
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_deletion_audit_deletion
+    ON account_deletion_audit (deletion_id, created_at);
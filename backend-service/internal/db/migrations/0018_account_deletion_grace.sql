@@ -0,0 +1,20 @@
+-- Deleted accounts are first deactivated and only purged once purge_after has passed.
+-- Until then the holder of the restore token can cancel the deletion.
+ALTER TABLE account_deletions
+    ADD COLUMN IF NOT EXISTS purge_after TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ADD COLUMN IF NOT EXISTS restore_token_hash TEXT,
+    ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS restored_at TIMESTAMPTZ;
+
+ALTER TABLE account_deletions DROP CONSTRAINT IF EXISTS account_deletions_status_check;
+ALTER TABLE account_deletions
+    ADD CONSTRAINT account_deletions_status_check CHECK (status IN ('pending', 'completed', 'restored'));
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_deletions_restore_token
+    ON account_deletions (restore_token_hash)
+    WHERE restore_token_hash IS NOT NULL;
+
+ALTER TABLE account_deletion_audit DROP CONSTRAINT IF EXISTS account_deletion_audit_event_check;
+ALTER TABLE account_deletion_audit
+    ADD CONSTRAINT account_deletion_audit_event_check
+        CHECK (event IN ('requested', 'deactivated', 'identity_deleted', 'failed', 'restored', 'completed'));
@@ -0,0 +1,14 @@
+-- Catalog snippets keep the hash history entries use (storage.ContentHash), so a run that
+-- names only a hash can be recognised as a catalog run.
+ALTER TABLE snippets
+    ADD COLUMN IF NOT EXISTS content_hash TEXT;
+
+UPDATE snippets
+SET content_hash = encode(sha256(convert_to(replace(content, E'\r\n', E'\n'), 'UTF8')), 'hex')
+WHERE content_hash IS NULL;
+
+ALTER TABLE snippets
+    ALTER COLUMN content_hash SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_snippets_content_hash
+    ON snippets (content_hash);
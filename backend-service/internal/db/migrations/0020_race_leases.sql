@@ -0,0 +1,10 @@
+-- Race rooms live in the memory of the replica that created them. That replica records itself
+-- in instance_id and keeps heartbeat_at fresh while the race is open, so other replicas can
+-- tell abandoned races from live ones.
+ALTER TABLE races
+    ADD COLUMN IF NOT EXISTS instance_id TEXT,
+    ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS idx_races_open_heartbeat
+    ON races (heartbeat_at)
+    WHERE status IN ('waiting', 'countdown', 'running');
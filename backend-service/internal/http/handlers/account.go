@@ -1,26 +1,52 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 
 	"code-type/backend/internal/http/middleware"
+	"code-type/backend/internal/kratos"
 	"code-type/backend/internal/services/account"
+	"code-type/backend/internal/storage"
 )
 
-// AccountHandler exposes account-related endpoints (e.g., self-service deletion).
+const maxRestoreBodyBytes = 4 << 10
+
+type deletionResponse struct {
+	Status       string    `json:"status"`
+	PurgeAfter   time.Time `json:"purge_after"`
+	RestoreToken string    `json:"restore_token,omitempty"`
+}
+
+type restoreAccountRequest struct {
+	Token string `json:"token"`
+}
+
+// AccountHandler exposes account-related endpoints (e.g., self-service deletion and data export).
 type AccountHandler struct {
-	service *account.Service
+	service  *account.Service
+	exporter *account.Exporter
 }
 
 // NewAccountHandler creates an AccountHandler instance.
-func NewAccountHandler(service *account.Service) *AccountHandler {
-	return &AccountHandler{service: service}
+func NewAccountHandler(service *account.Service, exporter *account.Exporter) *AccountHandler {
+	return &AccountHandler{
+		service:  service,
+		exporter: exporter,
+	}
 }
 
 // DeleteAccount removes the authenticated user's account and related data.
+// It responds 204 when everything is gone, and 202 when the deletion is pending: either the
+// account was deactivated and is purged after the grace period, or a step failed and is retried
+// in the background. The 202 body carries the purge time and, while the account can still be
+// restored, the token for RestoreAccount.
 func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.UserIDFromContext(r.Context())
 	if !ok || userID == "" {
@@ -33,11 +59,84 @@ func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.service.DeleteAccount(r.Context(), userID); err != nil {
+	result, err := h.service.DeleteAccount(r.Context(), userID)
+	if err != nil {
 		log.Printf("delete account failed for user %s: %v", userID, err)
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete account")
 		return
 	}
 
+	if !result.Completed {
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusAccepted, deletionResponse{
+			Status:       "pending",
+			PurgeAfter:   result.PurgeAfter,
+			RestoreToken: result.RestoreToken,
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreAccount cancels a pending deletion using the token returned when it was requested
+// and reactivates the account. It is public because a deactivated account cannot sign in.
+// Unknown tokens and tokens whose grace period has ended are both reported as 404.
+func (h *AccountHandler) RestoreAccount(w http.ResponseWriter, r *http.Request) {
+	var req restoreAccountRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRestoreBodyBytes)).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	err := h.service.RestoreAccount(r.Context(), req.Token)
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, kratos.ErrIdentityNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Restore token is invalid or expired")
+		return
+	}
+	if err != nil {
+		log.Printf("restore account failed: %v", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to restore account")
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// ExportAccount streams a ZIP archive with everything stored about the authenticated user:
+// the Kratos identity, profile, preferences, goals, achievements, teams, and the full history
+// including keystroke logs.
+func (h *AccountHandler) ExportAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User ID not found in request context")
+		return
+	}
+
+	if _, err := uuid.Parse(userID); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid user ID format")
+		return
+	}
+
+	export, err := h.exporter.Prepare(r.Context(), userID)
+	if errors.Is(err, kratos.ErrIdentityNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Account not found")
+		return
+	}
+	if err != nil {
+		log.Printf("prepare export failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to export account")
+		return
+	}
+
+	filename := fmt.Sprintf("codetype-export-%s.zip", export.ExportedAt().Format("20060102"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	// The status line is already sent, so a failure can only cut the archive short.
+	if err := export.WriteZip(r.Context(), w); err != nil {
+		log.Printf("export failed for user %s: %v", userID, err)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"code-type/backend/internal/http/middleware"
+	"code-type/backend/internal/services/achievements"
+)
+
+// AchievementsHandler serves the user's achievements.
+type AchievementsHandler struct {
+	engine *achievements.Engine
+}
+
+// NewAchievementsHandler creates a new AchievementsHandler.
+func NewAchievementsHandler(engine *achievements.Engine) *AchievementsHandler {
+	return &AchievementsHandler{engine: engine}
+}
+
+type achievementResponse struct {
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Unlocked    bool    `json:"unlocked"`
+	UnlockedAt  *string `json:"unlocked_at"`
+	HistoryID   *string `json:"history_id"`
+}
+
+// GetAchievements lists every configured achievement with its unlock time, if unlocked.
+// Achievements are evaluated in the background after each saved run, so a new unlock
+// may take a moment to appear.
+func (h *AchievementsHandler) GetAchievements(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	statuses, err := h.engine.List(r.Context(), userID)
+	if err != nil {
+		log.Printf("load achievements failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load achievements")
+		return
+	}
+
+	response := make([]achievementResponse, len(statuses))
+	for i, status := range statuses {
+		response[i] = achievementResponse{
+			ID:          status.Rule.ID,
+			Title:       status.Rule.Title,
+			Description: status.Rule.Description,
+			Unlocked:    status.Unlocked,
+		}
+
+		if status.Unlocked {
+			unlockedAt := status.UnlockedAt.Format(time.RFC3339)
+			response[i].UnlockedAt = &unlockedAt
+		}
+
+		if status.HistoryID != "" {
+			historyID := status.HistoryID
+			response[i].HistoryID = &historyID
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
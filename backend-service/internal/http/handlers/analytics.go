@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strings"
+
+	"code-type/backend/internal/http/middleware"
+	"code-type/backend/internal/storage"
+)
+
+const (
+	defaultBigramLimit          = 20
+	maxBigramLimit              = 100
+	defaultBigramMinOccurrences = 3
+)
+
+// AnalyticsHandler serves keystroke-level analytics.
+type AnalyticsHandler struct {
+	repo *storage.AnalyticsRepository
+}
+
+// NewAnalyticsHandler creates a new AnalyticsHandler.
+func NewAnalyticsHandler(repo *storage.AnalyticsRepository) *AnalyticsHandler {
+	return &AnalyticsHandler{repo: repo}
+}
+
+type keyAnalyticsResponse struct {
+	Key              string  `json:"key"`
+	Presses          int     `json:"presses"`
+	Errors           int     `json:"errors"`
+	Accuracy         float64 `json:"accuracy"`
+	AverageLatencyMs float64 `json:"average_latency_ms"`
+}
+
+type bigramAnalyticsResponse struct {
+	Bigram           string  `json:"bigram"`
+	Occurrences      int     `json:"occurrences"`
+	AverageLatencyMs float64 `json:"average_latency_ms"`
+}
+
+type keysAnalyticsResponse struct {
+	Language       string                    `json:"language,omitempty"`
+	Keys           []keyAnalyticsResponse    `json:"keys"`
+	SlowestBigrams []bigramAnalyticsResponse `json:"slowest_bigrams"`
+}
+
+// GetKeys returns per-key accuracy and latency plus the slowest bigrams, aggregated over all runs
+// that carried a keystroke log. Query parameters: language, bigrams (limit) and min_occurrences.
+func (h *AnalyticsHandler) GetKeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	query := r.URL.Query()
+	language := strings.ToLower(strings.TrimSpace(query.Get("language")))
+	limit := parseLimit(query.Get("bigrams"), defaultBigramLimit, maxBigramLimit)
+	minOccurrences := parseLimit(query.Get("min_occurrences"), defaultBigramMinOccurrences, math.MaxInt32)
+
+	keys, err := h.repo.KeyStats(r.Context(), userID, language)
+	if err != nil {
+		log.Printf("load key analytics failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load key analytics")
+		return
+	}
+
+	bigrams, err := h.repo.SlowestBigrams(r.Context(), userID, language, minOccurrences, limit)
+	if err != nil {
+		log.Printf("load bigram analytics failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load key analytics")
+		return
+	}
+
+	response := keysAnalyticsResponse{
+		Language:       language,
+		Keys:           make([]keyAnalyticsResponse, len(keys)),
+		SlowestBigrams: make([]bigramAnalyticsResponse, len(bigrams)),
+	}
+
+	for i, key := range keys {
+		accuracy := 100.0
+		if key.Presses > 0 {
+			accuracy = math.Round(float64(key.Presses-key.Errors)/float64(key.Presses)*1000) / 10
+		}
+
+		response.Keys[i] = keyAnalyticsResponse{
+			Key:              key.Key,
+			Presses:          key.Presses,
+			Errors:           key.Errors,
+			Accuracy:         accuracy,
+			AverageLatencyMs: key.AverageLatencyMs,
+		}
+	}
+
+	for i, bigram := range bigrams {
+		response.SlowestBigrams[i] = bigramAnalyticsResponse{
+			Bigram:           bigram.Bigram,
+			Occurrences:      bigram.Occurrences,
+			AverageLatencyMs: bigram.AverageLatencyMs,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"code-type/backend/internal/http/middleware"
+	"code-type/backend/internal/services/drills"
+)
+
+const (
+	defaultDrillSnippets = 3
+	maxDrillSnippets     = 10
+	defaultDrillLines    = 3
+	maxDrillLines        = 20
+)
+
+// DrillsHandler serves practice drills adapted to the user's weak keys.
+type DrillsHandler struct {
+	service *drills.Service
+}
+
+// NewDrillsHandler creates a new DrillsHandler.
+func NewDrillsHandler(service *drills.Service) *DrillsHandler {
+	return &DrillsHandler{service: service}
+}
+
+type drillFocusResponse struct {
+	Token  string  `json:"token"`
+	Weight float64 `json:"weight"`
+}
+
+type drillSnippetResponse struct {
+	snippetResponse
+	Score float64 `json:"score"`
+}
+
+// drillResponse carries the seed as a string because it may exceed the safe integer range of JSON clients.
+type drillResponse struct {
+	Seed     string                 `json:"seed"`
+	Language string                 `json:"language"`
+	Focus    []drillFocusResponse   `json:"focus"`
+	Snippets []drillSnippetResponse `json:"snippets"`
+	Lines    []string               `json:"lines"`
+}
+
+// GetNext returns catalog snippets and synthesized lines weighted toward the keys and bigrams
+// the user mistypes or types slowly. Query parameters: language (required), seed, snippets and lines.
+// The same seed and analytics reproduce the same drill; without a seed one is generated and returned.
+func (h *DrillsHandler) GetNext(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	query := r.URL.Query()
+
+	language := strings.ToLower(strings.TrimSpace(query.Get("language")))
+	if !languagePattern.MatchString(language) {
+		middleware.WriteError(w, http.StatusBadRequest, "language is required")
+		return
+	}
+
+	seed := rand.Uint64()
+	if raw := query.Get("seed"); raw != "" {
+		value, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			middleware.WriteError(w, http.StatusBadRequest, "seed must be a non-negative integer")
+			return
+		}
+		seed = value
+	}
+
+	drill, err := h.service.Next(r.Context(), userID, language, drills.Options{
+		Seed:     seed,
+		Snippets: parseLimit(query.Get("snippets"), defaultDrillSnippets, maxDrillSnippets),
+		Lines:    parseLimit(query.Get("lines"), defaultDrillLines, maxDrillLines),
+	})
+	if err != nil {
+		log.Printf("plan drill failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to generate drill")
+		return
+	}
+
+	response := drillResponse{
+		Seed:     strconv.FormatUint(drill.Seed, 10),
+		Language: language,
+		Focus:    make([]drillFocusResponse, len(drill.Weaknesses)),
+		Snippets: make([]drillSnippetResponse, len(drill.Snippets)),
+		Lines:    drill.Lines,
+	}
+
+	for i, weakness := range drill.Weaknesses {
+		response.Focus[i] = drillFocusResponse{Token: weakness.Token, Weight: weakness.Weight}
+	}
+
+	for i, scored := range drill.Snippets {
+		response.Snippets[i] = drillSnippetResponse{
+			snippetResponse: newSnippetResponse(scored.Snippet),
+			Score:           scored.Score,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
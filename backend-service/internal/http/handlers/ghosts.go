@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"code-type/backend/internal/http/middleware"
+	"code-type/backend/internal/storage"
+	"code-type/backend/internal/typing"
+)
+
+// GhostsHandler serves timelines of past runs to race against.
+type GhostsHandler struct {
+	repo     *storage.HistoryRepository
+	snippets *storage.SnippetRepository
+}
+
+// NewGhostsHandler creates a new GhostsHandler.
+func NewGhostsHandler(repo *storage.HistoryRepository, snippets *storage.SnippetRepository) *GhostsHandler {
+	return &GhostsHandler{
+		repo:     repo,
+		snippets: snippets,
+	}
+}
+
+type ghostPointResponse struct {
+	T        int `json:"t"`
+	Position int `json:"position"`
+}
+
+// ghostResponse never carries the owner's identity ID; other users' runs are labelled by display name.
+type ghostResponse struct {
+	HistoryID       string               `json:"history_id"`
+	Own             bool                 `json:"own"`
+	DisplayName     string               `json:"display_name,omitempty"`
+	Language        string               `json:"language"`
+	SnippetID       string               `json:"snippet_id,omitempty"`
+	SnippetHash     string               `json:"snippet_hash,omitempty"`
+	Snippet         string               `json:"snippet,omitempty"`
+	WPM             int                  `json:"wpm"`
+	Accuracy        int                  `json:"accuracy"`
+	Errors          int                  `json:"errors"`
+	DurationSeconds int                  `json:"duration_seconds"`
+	CompletedAt     string               `json:"completed_at"`
+	Timeline        []ghostPointResponse `json:"timeline"`
+}
+
+// GetGhost returns the cursor timeline of a run so the client can race against it.
+// The caller's own runs are always visible; other runs only when they are verified and their owner
+// opted in to leaderboards. Invisible runs and runs without a keystroke log return 404.
+func (h *GhostsHandler) GetGhost(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	id := chi.URLParam(r, "history_id")
+	if _, err := uuid.Parse(id); err != nil {
+		middleware.WriteError(w, http.StatusNotFound, "Ghost not found")
+		return
+	}
+
+	ghost, err := h.repo.Ghost(r.Context(), userID, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Ghost not found")
+		return
+	}
+	if err != nil {
+		log.Printf("load ghost %s failed: %v", id, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load ghost")
+		return
+	}
+
+	entry := ghost.Entry
+	response := ghostResponse{
+		HistoryID:       entry.ID,
+		Own:             entry.UserID == userID,
+		Language:        entry.Language,
+		SnippetID:       entry.SnippetID,
+		SnippetHash:     entry.SnippetHash,
+		Snippet:         typedSnippetContent(r.Context(), h.snippets, entry),
+		WPM:             entry.WPM,
+		Accuracy:        entry.Accuracy,
+		Errors:          entry.Errors,
+		DurationSeconds: entry.DurationSeconds,
+		CompletedAt:     entry.CompletedAt.Format(time.RFC3339),
+	}
+
+	if !response.Own {
+		response.DisplayName = ghost.DisplayName
+	}
+
+	timeline := typing.Timeline(ghost.Keystrokes)
+	response.Timeline = make([]ghostPointResponse, len(timeline))
+	for i, point := range timeline {
+		response.Timeline[i] = ghostPointResponse{T: point.OffsetMillis, Position: point.Position}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"code-type/backend/internal/http/middleware"
+	"code-type/backend/internal/storage"
+)
+
+const maxGoals = 20
+
+// goalTargetLimits bounds the target of each goal kind.
+var goalTargetLimits = map[string]int{
+	storage.GoalDailyMinutes: 24 * 60,
+	storage.GoalWeeklyRuns:   1000,
+	storage.GoalTargetWPM:    300,
+}
+
+// GoalsHandler serves practice goals, progress toward them and practice streaks.
+type GoalsHandler struct {
+	repo *storage.GoalRepository
+}
+
+// NewGoalsHandler creates a new GoalsHandler.
+func NewGoalsHandler(repo *storage.GoalRepository) *GoalsHandler {
+	return &GoalsHandler{repo: repo}
+}
+
+// RegisterRoutes mounts goal routes on the provided router.
+func (h *GoalsHandler) RegisterRoutes(router chi.Router) {
+	router.Get("/", h.handleGetGoals)
+	router.Put("/", h.handlePutGoals)
+	router.Get("/progress", h.handleGetProgress)
+}
+
+type goalPayload struct {
+	Kind     string `json:"kind"`
+	Language string `json:"language,omitempty"`
+	Target   int    `json:"target"`
+}
+
+type goalsPayload struct {
+	TimeZone string        `json:"time_zone"`
+	Goals    []goalPayload `json:"goals"`
+}
+
+type goalProgressResponse struct {
+	goalPayload
+	Current  int  `json:"current"`
+	Achieved bool `json:"achieved"`
+}
+
+type streakResponse struct {
+	Current       int     `json:"current"`
+	Longest       int     `json:"longest"`
+	LastPracticed *string `json:"last_practiced"`
+}
+
+type goalsProgressResponse struct {
+	TimeZone string                 `json:"time_zone"`
+	Date     string                 `json:"date"`
+	Streak   streakResponse         `json:"streak"`
+	Goals    []goalProgressResponse `json:"goals"`
+}
+
+func (h *GoalsHandler) handleGetGoals(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	settings, err := h.repo.Get(r.Context(), userID)
+	if err != nil {
+		log.Printf("load goals failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load goals")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newGoalsPayload(settings))
+}
+
+// handlePutGoals replaces the user's time zone and complete goal set.
+func (h *GoalsHandler) handlePutGoals(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req goalsPayload
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	settings, err := validateGoalsRequest(req)
+	if err != nil {
+		middleware.WriteError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	if err := h.repo.Replace(r.Context(), userID, settings); err != nil {
+		log.Printf("save goals failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to save goals")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newGoalsPayload(settings))
+}
+
+// handleGetProgress reports each goal against the current local day (daily_minutes),
+// the current local week starting on Monday (weekly_runs) or the best run so far (target_wpm),
+// plus the current and longest streaks of consecutive local days with practice.
+// A streak stays current until a full local day passes without practice.
+func (h *GoalsHandler) handleGetProgress(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	settings, err := h.repo.Get(r.Context(), userID)
+	if err != nil {
+		log.Printf("load goals failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load goal progress")
+		return
+	}
+
+	location, err := parseTimeZone(settings.TimeZone)
+	if err != nil {
+		log.Printf("stored time zone %q is invalid for user %s: %v", settings.TimeZone, userID, err)
+		location = time.UTC
+	}
+
+	now := time.Now().In(location)
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	weekStart := dayStart.AddDate(0, 0, -((int(dayStart.Weekday()) + 6) % 7))
+
+	streaks, err := h.repo.Streaks(r.Context(), userID, location.String())
+	if err != nil {
+		log.Printf("load streaks failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load goal progress")
+		return
+	}
+
+	response := goalsProgressResponse{
+		TimeZone: location.String(),
+		Date:     dayStart.Format(time.DateOnly),
+		Streak:   newStreakResponse(streaks, dayStart),
+		Goals:    make([]goalProgressResponse, len(settings.Goals)),
+	}
+
+	totalsByLanguage := make(map[string]storage.GoalTotals)
+	for i, goal := range settings.Goals {
+		totals, ok := totalsByLanguage[goal.Language]
+		if !ok {
+			totals, err = h.repo.Totals(r.Context(), userID, goal.Language, dayStart, weekStart)
+			if err != nil {
+				log.Printf("load goal totals failed for user %s: %v", userID, err)
+				middleware.WriteError(w, http.StatusInternalServerError, "Failed to load goal progress")
+				return
+			}
+			totalsByLanguage[goal.Language] = totals
+		}
+
+		current := 0
+		switch goal.Kind {
+		case storage.GoalDailyMinutes:
+			current = totals.TodaySeconds / 60
+		case storage.GoalWeeklyRuns:
+			current = totals.WeekRuns
+		case storage.GoalTargetWPM:
+			current = totals.BestWPM
+		}
+
+		response.Goals[i] = goalProgressResponse{
+			goalPayload: goalPayload{Kind: goal.Kind, Language: goal.Language, Target: goal.Target},
+			Current:     current,
+			Achieved:    current >= goal.Target,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func validateGoalsRequest(req goalsPayload) (storage.GoalSettings, error) {
+	location, err := parseTimeZone(strings.TrimSpace(req.TimeZone))
+	if err != nil {
+		return storage.GoalSettings{}, errValidation("time_zone must be an IANA time zone name")
+	}
+
+	if len(req.Goals) > maxGoals {
+		return storage.GoalSettings{}, errValidation("too many goals")
+	}
+
+	settings := storage.GoalSettings{TimeZone: location.String(), Goals: make([]storage.Goal, 0, len(req.Goals))}
+	seen := make(map[string]bool)
+	for _, goal := range req.Goals {
+		maxTarget, ok := goalTargetLimits[goal.Kind]
+		if !ok {
+			return storage.GoalSettings{}, errValidation("kind must be one of daily_minutes, weekly_runs, target_wpm")
+		}
+
+		language := strings.ToLower(strings.TrimSpace(goal.Language))
+		if language != "" && !languagePattern.MatchString(language) {
+			return storage.GoalSettings{}, errValidation("language must be a lowercase identifier of up to 32 characters")
+		}
+
+		if goal.Target <= 0 || goal.Target > maxTarget {
+			return storage.GoalSettings{}, errValidation("target is out of range for " + goal.Kind)
+		}
+
+		key := goal.Kind + "/" + language
+		if seen[key] {
+			return storage.GoalSettings{}, errValidation("duplicate goal for " + goal.Kind)
+		}
+		seen[key] = true
+
+		settings.Goals = append(settings.Goals, storage.Goal{Kind: goal.Kind, Language: language, Target: goal.Target})
+	}
+
+	return settings, nil
+}
+
+// newStreakResponse derives the current and longest streak from streaks ordered most recent first.
+func newStreakResponse(streaks []storage.PracticeStreak, today time.Time) streakResponse {
+	response := streakResponse{}
+	if len(streaks) == 0 {
+		return response
+	}
+
+	for _, streak := range streaks {
+		response.Longest = max(response.Longest, streak.Days)
+	}
+
+	// Streak dates are local calendar dates stored at midnight UTC.
+	todayDate := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	latest := streaks[0]
+	if !latest.End.Before(todayDate.AddDate(0, 0, -1)) {
+		response.Current = latest.Days
+	}
+
+	lastPracticed := latest.End.Format(time.DateOnly)
+	response.LastPracticed = &lastPracticed
+
+	return response
+}
+
+func newGoalsPayload(settings storage.GoalSettings) goalsPayload {
+	payload := goalsPayload{TimeZone: settings.TimeZone, Goals: make([]goalPayload, len(settings.Goals))}
+	for i, goal := range settings.Goals {
+		payload.Goals[i] = goalPayload{Kind: goal.Kind, Language: goal.Language, Target: goal.Target}
+	}
+
+	return payload
+}
@@ -1,16 +1,22 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 
 	"code-type/backend/internal/http/middleware"
 	"code-type/backend/internal/storage"
+	"code-type/backend/internal/typing"
 )
 
 const (
@@ -18,14 +24,20 @@ const (
 	maxHistoryLimit     = 100
 )
 
+const maxHistoryBodyBytes = 4 << 20
+
+var snippetHashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
 // HistoryHandler provides HTTP handlers for practice history operations.
 type HistoryHandler struct {
-	repo *storage.HistoryRepository
+	repo     *storage.HistoryRepository
+	snippets *storage.SnippetRepository
 }
 
 // NewHistoryHandler creates a new HistoryHandler.
-func NewHistoryHandler(repo *storage.HistoryRepository) *HistoryHandler {
-	return &HistoryHandler{repo: repo}
+// The snippet repository is the source of truth for supported languages.
+func NewHistoryHandler(repo *storage.HistoryRepository, snippets *storage.SnippetRepository) *HistoryHandler {
+	return &HistoryHandler{repo: repo, snippets: snippets}
 }
 
 // RegisterRoutes mounts history routes on the provided router.
@@ -33,27 +45,53 @@ func (h *HistoryHandler) RegisterRoutes(router chi.Router) {
 	router.Get("/", h.handleListHistory)
 	router.Post("/", h.handleCreateHistory)
 	router.Delete("/", h.handleDeleteHistory)
+	router.Get("/best", h.handleBestHistory)
+	router.Get("/export", h.handleExportHistory)
+	router.Post("/import", h.handleImportHistory)
+	router.Post("/batch", h.handleBatchHistory)
+	router.Get("/{id}", h.handleGetHistoryEntry)
+	router.Delete("/{id}", h.handleDeleteHistoryEntry)
+	router.Get("/{id}/replay", h.handleGetReplay)
 }
 
 type historyEntryResponse struct {
-	ID          string `json:"id"`
-	Language    string `json:"language"`
-	WPM         int    `json:"wpm"`
-	Accuracy    int    `json:"accuracy"`
-	Errors      int    `json:"errors"`
-	Time        int    `json:"time"`
-	Date        string `json:"date"`
-	CreatedAt   string `json:"created_at"`
-	CompletedAt string `json:"completed_at"`
+	ID                 string `json:"id"`
+	Language           string `json:"language"`
+	SnippetID          string `json:"snippet_id,omitempty"`
+	SnippetHash        string `json:"snippet_hash,omitempty"`
+	RaceID             string `json:"race_id,omitempty"`
+	WPM                int    `json:"wpm"`
+	Accuracy           int    `json:"accuracy"`
+	Errors             int    `json:"errors"`
+	Time               int    `json:"time"`
+	Date               string `json:"date"`
+	CreatedAt          string `json:"created_at"`
+	CompletedAt        string `json:"completed_at"`
+	VerificationStatus string `json:"verification_status"`
 }
 
+// createHistoryRequest is the payload for a finished run.
+// SnippetID links a catalog snippet; SnippetHash (hex SHA-256 of the text) identifies snippets outside the catalog.
+// Keystrokes is an optional log of every key press, used for replays.
 type createHistoryRequest struct {
-	Language string `json:"language"`
-	WPM      int    `json:"wpm"`
-	Accuracy int    `json:"accuracy"`
-	Errors   int    `json:"errors"`
-	Time     int    `json:"time"`
-	Date     string `json:"date"`
+	Language    string             `json:"language"`
+	SnippetID   string             `json:"snippet_id"`
+	SnippetHash string             `json:"snippet_hash"`
+	WPM         int                `json:"wpm"`
+	Accuracy    int                `json:"accuracy"`
+	Errors      int                `json:"errors"`
+	Time        int                `json:"time"`
+	Date        string             `json:"date"`
+	Keystrokes  []keystrokePayload `json:"keystrokes,omitempty"`
+}
+
+// keystrokePayload is the compact wire form of a key press:
+// c is the typed character, t the offset in milliseconds from the start of the run
+// (paused time excluded) and ok whether it matched the expected character.
+type keystrokePayload struct {
+	C  string `json:"c"`
+	T  int    `json:"t"`
+	OK bool   `json:"ok"`
 }
 
 func (h *HistoryHandler) handleListHistory(w http.ResponseWriter, r *http.Request) {
@@ -63,32 +101,65 @@ func (h *HistoryHandler) handleListHistory(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	limit := parseLimit(r.URL.Query().Get("limit"))
-	offset := parseOffset(r.URL.Query().Get("offset"))
+	filter, sort, err := parseHistoryQuery(r)
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query := r.URL.Query()
+	params := storage.ListHistoryParams{
+		UserID: userID,
+		Filter: filter,
+		Sort:   sort,
+		Limit:  parseLimit(query.Get("limit"), defaultHistoryLimit, maxHistoryLimit),
+		Offset: parseOffset(query.Get("offset")),
+	}
+
+	// Cursor mode is selected by the presence of the cursor parameter (empty for the first page)
+	// and responds with an envelope. Without it the legacy offset mode returns a bare array.
+	cursorMode := query.Has("cursor")
+	if cursorMode {
+		if query.Has("offset") {
+			middleware.WriteError(w, http.StatusBadRequest, "cursor and offset cannot be combined")
+			return
+		}
+
+		params.After, err = decodeHistoryCursor(query.Get("cursor"), sort)
+		if err != nil {
+			middleware.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		// Fetch one extra row to learn whether another page exists.
+		params.Limit++
+	}
 
-	entries, err := h.repo.ListByUser(r.Context(), userID, limit, offset)
+	entries, err := h.repo.ListByUser(r.Context(), params)
 	if err != nil {
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load history")
 		return
 	}
 
-	response := make([]historyEntryResponse, len(entries))
+	var nextCursor *string
+	if cursorMode && len(entries) == params.Limit {
+		entries = entries[:params.Limit-1]
+		token := encodeHistoryCursor(entries[len(entries)-1], sort)
+		nextCursor = &token
+	}
+
+	items := make([]historyEntryResponse, len(entries))
 	for i, entry := range entries {
-		response[i] = historyEntryResponse{
-			ID:          entry.ID,
-			Language:    entry.Language,
-			WPM:         entry.WPM,
-			Accuracy:    entry.Accuracy,
-			Errors:      entry.Errors,
-			Time:        entry.DurationSeconds,
-			Date:        entry.CompletedAt.Format(time.RFC3339),
-			CreatedAt:   entry.CreatedAt.Format(time.RFC3339),
-			CompletedAt: entry.CompletedAt.Format(time.RFC3339),
-		}
+		items[i] = newHistoryEntryResponse(entry)
+	}
+
+	if cursorMode {
+		writeJSON(w, http.StatusOK, historyPageResponse{Items: items, NextCursor: nextCursor})
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	if err := json.NewEncoder(w).Encode(items); err != nil {
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to encode response")
 		return
 	}
@@ -102,31 +173,59 @@ func (h *HistoryHandler) handleCreateHistory(w http.ResponseWriter, r *http.Requ
 	}
 
 	var req createHistoryRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxHistoryBodyBytes)).Decode(&req); err != nil {
 		middleware.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
 		return
 	}
 
-	if err := validateHistoryRequest(req); err != nil {
-		middleware.WriteError(w, http.StatusUnprocessableEntity, err.Error())
+	key, err := parseIdempotencyKey(r, req)
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	completedAt, err := time.Parse(time.RFC3339, req.Date)
+	// A retried request is answered from the stored entry before anything is validated again,
+	// so it gets the original response even if, say, its snippet has since left the catalog.
+	if key != nil {
+		entry, err := h.repo.Replay(r.Context(), userID, *key)
+		if err == nil {
+			writeHistoryReplay(w, entry)
+			return
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			writeIdempotencyError(w, userID, err)
+			return
+		}
+	}
+
+	languages, err := supportedLanguages(r.Context(), h.snippets)
 	if err != nil {
-		middleware.WriteError(w, http.StatusBadRequest, "Invalid date format, expected RFC3339")
+		log.Printf("load supported languages failed: %v", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to validate history entry")
 		return
 	}
 
-	entry, err := h.repo.Create(r.Context(), storage.CreateHistoryParams{
-		UserID:          userID,
-		Language:        req.Language,
-		WPM:             req.WPM,
-		Accuracy:        req.Accuracy,
-		Errors:          req.Errors,
-		DurationSeconds: req.Time,
-		CompletedAt:     completedAt,
-	})
+	params, err := h.buildCreateParams(r.Context(), userID, req, languages, newSnippetLookup(h.snippets))
+	if err != nil {
+		writeCreateHistoryError(w, err)
+		return
+	}
+
+	var entry storage.HistoryEntry
+	if key == nil {
+		entry, err = h.repo.Create(r.Context(), params)
+	} else {
+		var replayed bool
+		entry, replayed, err = h.repo.CreateIdempotent(r.Context(), params, *key)
+		if err == nil && replayed {
+			writeHistoryReplay(w, entry)
+			return
+		}
+		if errors.Is(err, storage.ErrIdempotencyMismatch) {
+			writeIdempotencyError(w, userID, err)
+			return
+		}
+	}
 	if err != nil {
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to save history entry")
 		return
@@ -134,17 +233,7 @@ func (h *HistoryHandler) handleCreateHistory(w http.ResponseWriter, r *http.Requ
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	if err := json.NewEncoder(w).Encode(historyEntryResponse{
-		ID:          entry.ID,
-		Language:    entry.Language,
-		WPM:         entry.WPM,
-		Accuracy:    entry.Accuracy,
-		Errors:      entry.Errors,
-		Time:        entry.DurationSeconds,
-		Date:        entry.CompletedAt.Format(time.RFC3339),
-		CreatedAt:   entry.CreatedAt.Format(time.RFC3339),
-		CompletedAt: entry.CompletedAt.Format(time.RFC3339),
-	}); err != nil {
+	if err := json.NewEncoder(w).Encode(newHistoryEntryResponse(entry)); err != nil {
 		middleware.WriteError(w, http.StatusInternalServerError, "Failed to encode response")
 		return
 	}
@@ -165,12 +254,248 @@ func (h *HistoryHandler) handleDeleteHistory(w http.ResponseWriter, r *http.Requ
 	w.WriteHeader(http.StatusNoContent)
 }
 
-func validateHistoryRequest(req createHistoryRequest) error {
+// handleGetHistoryEntry returns a single run owned by the caller.
+// Unknown IDs and other users' runs both yield 404 so row existence is not leaked.
+func (h *HistoryHandler) handleGetHistoryEntry(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		middleware.WriteError(w, http.StatusNotFound, "History entry not found")
+		return
+	}
+
+	entry, err := h.repo.GetByID(r.Context(), userID, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "History entry not found")
+		return
+	}
+	if err != nil {
+		log.Printf("load history entry %s failed: %v", id, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load history entry")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newHistoryEntryResponse(entry))
+}
+
+// handleDeleteHistoryEntry removes a single run owned by the caller.
+func (h *HistoryHandler) handleDeleteHistoryEntry(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		middleware.WriteError(w, http.StatusNotFound, "History entry not found")
+		return
+	}
+
+	err := h.repo.DeleteByID(r.Context(), userID, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "History entry not found")
+		return
+	}
+	if err != nil {
+		log.Printf("delete history entry %s failed: %v", id, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete history entry")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleBestHistory returns the user's personal best on the snippet given by snippet_id or snippet_hash.
+func (h *HistoryHandler) handleBestHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	snippetID, snippetHash, err := parseSnippetReference(r)
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if snippetID == "" && snippetHash == "" {
+		middleware.WriteError(w, http.StatusBadRequest, "snippet_id or snippet_hash is required")
+		return
+	}
+
+	entry, err := h.repo.BestBySnippet(r.Context(), userID, snippetID, snippetHash)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "No runs recorded for this snippet")
+		return
+	}
+	if err != nil {
+		log.Printf("load personal best failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load personal best")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newHistoryEntryResponse(entry))
+}
+
+// errInvalidDate is reported with 400 rather than 422 to keep the original API contract.
+var errInvalidDate = errValidation("Invalid date format, expected RFC3339")
+
+// buildCreateParams validates a submitted run, resolves its snippet and verifies it server-side.
+// Client errors are returned as validationError values; anything else is an internal failure.
+// Runs carrying a keystroke log are stored with the server's recomputed numbers.
+// Snippets are resolved through snippets, which callers share across the runs of one request.
+func (h *HistoryHandler) buildCreateParams(
+	ctx context.Context,
+	userID string,
+	req createHistoryRequest,
+	languages map[string]bool,
+	snippets *snippetLookup,
+) (storage.CreateHistoryParams, error) {
+	if err := validateHistoryRequest(req, languages); err != nil {
+		return storage.CreateHistoryParams{}, err
+	}
+
+	completedAt, err := time.Parse(time.RFC3339, req.Date)
+	if err != nil {
+		return storage.CreateHistoryParams{}, errInvalidDate
+	}
+
+	content, snippetHash, err := resolveSnippet(ctx, snippets, req)
+	if err != nil {
+		return storage.CreateHistoryParams{}, err
+	}
+
+	params := storage.CreateHistoryParams{
+		UserID:          userID,
+		Language:        req.Language,
+		SnippetID:       req.SnippetID,
+		SnippetHash:     snippetHash,
+		WPM:             req.WPM,
+		Accuracy:        req.Accuracy,
+		Errors:          req.Errors,
+		DurationSeconds: req.Time,
+		CompletedAt:     completedAt,
+		Keystrokes:      toKeystrokes(req.Keystrokes),
+	}
+
+	verdict := typing.Verify(typing.Run{
+		WPM:             req.WPM,
+		Accuracy:        req.Accuracy,
+		Errors:          req.Errors,
+		DurationSeconds: req.Time,
+		Snippet:         content,
+		Keystrokes:      params.Keystrokes,
+	}, typing.DefaultLimits)
+
+	if verdict.Rejected {
+		return storage.CreateHistoryParams{}, errValidation("implausible run: " + strings.Join(verdict.Reasons, "; "))
+	}
+
+	if verdict.Status == typing.StatusFlagged {
+		log.Printf("flagged run for user %s: %s", userID, strings.Join(verdict.Reasons, "; "))
+	}
+
+	params.VerificationStatus = verdict.Status
+	if len(params.Keystrokes) > 0 {
+		params.KeyStats, params.BigramStats = typing.Summarize(content, params.Keystrokes)
+	}
+
+	if verdict.Recomputed {
+		params.WPM = verdict.WPM
+		params.Accuracy = verdict.Accuracy
+		params.Errors = verdict.Errors
+		params.DurationSeconds = verdict.DurationSeconds
+	}
+
+	return params, nil
+}
+
+// writeCreateHistoryError maps buildCreateParams errors to HTTP responses.
+func writeCreateHistoryError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errInvalidDate) {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var validationErr validationError
+	if errors.As(err, &validationErr) {
+		middleware.WriteError(w, http.StatusUnprocessableEntity, validationErr.Error())
+		return
+	}
+
+	log.Printf("prepare history entry failed: %v", err)
+	middleware.WriteError(w, http.StatusInternalServerError, "Failed to validate history entry")
+}
+
+// resolveSnippet returns the snippet text (when known) and the content hash to store with a run.
+// Catalog snippets are looked up so the hash always reflects the server's copy of the text,
+// and must belong to the submitted language. Runs on snippets outside the catalog keep the client's hash;
+// a bare hash of a catalog snippet is refused, since its run could not be checked against the text.
+func resolveSnippet(ctx context.Context, snippets *snippetLookup, req createHistoryRequest) (string, string, error) {
+	if req.SnippetID == "" {
+		if req.SnippetHash == "" {
+			return "", "", nil
+		}
+
+		catalog, err := snippets.isCatalogHash(ctx, req.SnippetHash)
+		if err != nil {
+			return "", "", err
+		}
+		if catalog {
+			return "", "", errValidation("snippet_id is required for catalog snippets")
+		}
+
+		return "", req.SnippetHash, nil
+	}
+
+	snippet, err := snippets.get(ctx, req.SnippetID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return "", "", errValidation("snippet not found")
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	if snippet.Language != req.Language {
+		return "", "", errValidation("snippet language does not match language")
+	}
+
+	hash := storage.ContentHash(snippet.Content)
+	if req.SnippetHash != "" && req.SnippetHash != hash {
+		return "", "", errValidation("snippet_hash does not match snippet content")
+	}
+
+	return strings.ReplaceAll(snippet.Content, "\r\n", "\n"), hash, nil
+}
+
+// supportedLanguages returns the set of languages present in the snippet catalog.
+func supportedLanguages(ctx context.Context, snippets *storage.SnippetRepository) (map[string]bool, error) {
+	languages, err := snippets.Languages(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	supported := make(map[string]bool, len(languages))
+	for _, language := range languages {
+		supported[language] = true
+	}
+
+	return supported, nil
+}
+
+func validateHistoryRequest(req createHistoryRequest, languages map[string]bool) error {
 	if req.Language == "" {
 		return errValidation("language is required")
 	}
 
-	if !isSupportedLanguage(req.Language) {
+	if !languages[req.Language] {
 		return errValidation("unsupported language")
 	}
 
@@ -194,30 +519,93 @@ func validateHistoryRequest(req createHistoryRequest) error {
 		return errValidation("date is required")
 	}
 
+	if req.SnippetID != "" {
+		if _, err := uuid.Parse(req.SnippetID); err != nil {
+			return errValidation("snippet_id must be a UUID")
+		}
+	}
+
+	if req.SnippetHash != "" && !snippetHashPattern.MatchString(req.SnippetHash) {
+		return errValidation("snippet_hash must be a lowercase hex SHA-256 digest")
+	}
+
+	return validateKeystrokes(req.Keystrokes)
+}
+
+// validateKeystrokes applies the shared keystroke log checks to a submitted log.
+func validateKeystrokes(keystrokes []keystrokePayload) error {
+	if len(keystrokes) > typing.MaxKeystrokes {
+		return errValidation("too many keystrokes")
+	}
+
+	if err := typing.ValidateKeystrokes(toKeystrokes(keystrokes)); err != nil {
+		return errValidation(err.Error())
+	}
+
 	return nil
 }
 
-func isSupportedLanguage(language string) bool {
-	switch language {
-	case "javascript", "python", "go":
-		return true
-	default:
-		return false
+func toKeystrokes(payload []keystrokePayload) []typing.Keystroke {
+	if len(payload) == 0 {
+		return nil
+	}
+
+	keystrokes := make([]typing.Keystroke, len(payload))
+	for i, k := range payload {
+		keystrokes[i] = typing.Keystroke{Char: k.C, OffsetMillis: k.T, Correct: k.OK}
+	}
+
+	return keystrokes
+}
+
+// parseSnippetReference reads the optional snippet_id and snippet_hash query parameters.
+func parseSnippetReference(r *http.Request) (string, string, error) {
+	snippetID := r.URL.Query().Get("snippet_id")
+	if snippetID != "" {
+		if _, err := uuid.Parse(snippetID); err != nil {
+			return "", "", errValidation("snippet_id must be a UUID")
+		}
+	}
+
+	snippetHash := strings.ToLower(r.URL.Query().Get("snippet_hash"))
+	if snippetHash != "" && !snippetHashPattern.MatchString(snippetHash) {
+		return "", "", errValidation("snippet_hash must be a hex SHA-256 digest")
+	}
+
+	return snippetID, snippetHash, nil
+}
+
+func newHistoryEntryResponse(entry storage.HistoryEntry) historyEntryResponse {
+	return historyEntryResponse{
+		ID:                 entry.ID,
+		Language:           entry.Language,
+		SnippetID:          entry.SnippetID,
+		SnippetHash:        entry.SnippetHash,
+		RaceID:             entry.RaceID,
+		WPM:                entry.WPM,
+		Accuracy:           entry.Accuracy,
+		Errors:             entry.Errors,
+		Time:               entry.DurationSeconds,
+		Date:               entry.CompletedAt.Format(time.RFC3339),
+		CreatedAt:          entry.CreatedAt.Format(time.RFC3339),
+		CompletedAt:        entry.CompletedAt.Format(time.RFC3339),
+		VerificationStatus: entry.VerificationStatus,
 	}
 }
 
-func parseLimit(raw string) int {
+// parseLimit parses a page size, falling back to def for invalid values and capping at max.
+func parseLimit(raw string, def, maxLimit int) int {
 	if raw == "" {
-		return defaultHistoryLimit
+		return def
 	}
 
 	value, err := strconv.Atoi(raw)
 	if err != nil || value <= 0 {
-		return defaultHistoryLimit
+		return def
 	}
 
-	if value > maxHistoryLimit {
-		return maxHistoryLimit
+	if value > maxLimit {
+		return maxLimit
 	}
 
 	return value
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"code-type/backend/internal/http/middleware"
+)
+
+const (
+	maxBatchEntries   = 100
+	maxBatchBodyBytes = 32 << 20
+)
+
+// Item outcomes reported by the batch endpoint. Failed items hit a server error
+// and should be sent again; invalid ones never will be accepted.
+const (
+	batchStatusCreated   = "created"
+	batchStatusDuplicate = "duplicate"
+	batchStatusInvalid   = "invalid"
+	batchStatusFailed    = "failed"
+)
+
+// batchHistoryItem is a run recorded offline: the usual payload plus the client's ID for it.
+type batchHistoryItem struct {
+	ClientID string `json:"client_id"`
+	createHistoryRequest
+}
+
+type batchHistoryRequest struct {
+	Entries []json.RawMessage `json:"entries"`
+}
+
+type batchItemResponse struct {
+	ClientID string                `json:"client_id"`
+	Status   string                `json:"status"`
+	Error    string                `json:"error,omitempty"`
+	Entry    *historyEntryResponse `json:"entry,omitempty"`
+}
+
+type batchHistoryResponse struct {
+	Items []batchItemResponse `json:"items"`
+}
+
+// handleBatchHistory syncs runs queued while offline. Each entry is validated and saved on its own,
+// so one bad or failed entry does not affect the others; the response has one item per entry in
+// request order. Entries whose client_id was synced before are reported as duplicates with the
+// stored run, which makes resending a whole batch safe.
+func (h *HistoryHandler) handleBatchHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req batchHistoryRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBodyBytes)).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if len(req.Entries) == 0 || len(req.Entries) > maxBatchEntries {
+		middleware.WriteError(w, http.StatusUnprocessableEntity, fmt.Sprintf("entries must contain between 1 and %d runs", maxBatchEntries))
+		return
+	}
+
+	languages, err := supportedLanguages(r.Context(), h.snippets)
+	if err != nil {
+		log.Printf("load supported languages failed: %v", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to validate history entries")
+		return
+	}
+
+	snippets := newSnippetLookup(h.snippets)
+	response := batchHistoryResponse{Items: make([]batchItemResponse, len(req.Entries))}
+	for i, raw := range req.Entries {
+		response.Items[i] = h.syncBatchItem(r, userID, raw, languages, snippets)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *HistoryHandler) syncBatchItem(
+	r *http.Request,
+	userID string,
+	raw json.RawMessage,
+	languages map[string]bool,
+	snippets *snippetLookup,
+) batchItemResponse {
+	var item batchHistoryItem
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return batchItemResponse{Status: batchStatusInvalid, Error: "entry must be a JSON object"}
+	}
+
+	result := batchItemResponse{ClientID: item.ClientID}
+	if _, err := uuid.Parse(item.ClientID); err != nil {
+		result.Status, result.Error = batchStatusInvalid, "client_id must be a UUID"
+		return result
+	}
+
+	params, err := h.buildCreateParams(r.Context(), userID, item.createHistoryRequest, languages, snippets)
+	var validationErr validationError
+	if errors.As(err, &validationErr) {
+		result.Status, result.Error = batchStatusInvalid, err.Error()
+		return result
+	}
+	if err != nil {
+		log.Printf("validate batch entry %s failed for user %s: %v", item.ClientID, userID, err)
+		result.Status, result.Error = batchStatusFailed, "Failed to validate history entry"
+		return result
+	}
+
+	params.ClientID = item.ClientID
+	entry, duplicate, err := h.repo.CreateSynced(r.Context(), params)
+	if err != nil {
+		log.Printf("sync batch entry %s failed for user %s: %v", item.ClientID, userID, err)
+		result.Status, result.Error = batchStatusFailed, "Failed to save history entry"
+		return result
+	}
+
+	result.Status = batchStatusCreated
+	if duplicate {
+		result.Status = batchStatusDuplicate
+	}
+
+	response := newHistoryEntryResponse(entry)
+	result.Entry = &response
+	return result
+}
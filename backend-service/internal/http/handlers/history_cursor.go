@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+
+	"code-type/backend/internal/storage"
+)
+
+// historyCursorPayload is the JSON document behind the opaque next_cursor token.
+// Clients must treat the token as opaque; the layout may change between releases.
+// The sort settings are embedded so a cursor cannot be replayed against a different ordering.
+type historyCursorPayload struct {
+	Sort        string `json:"s"`
+	Ascending   bool   `json:"a,omitempty"`
+	Value       int    `json:"v,omitempty"`
+	CompletedAt string `json:"t"`
+	ID          string `json:"id"`
+}
+
+type historyPageResponse struct {
+	Items      []historyEntryResponse `json:"items"`
+	NextCursor *string                `json:"next_cursor"`
+}
+
+// encodeHistoryCursor produces the opaque token pointing after the given entry in the given order.
+func encodeHistoryCursor(entry storage.HistoryEntry, sort storage.HistorySort) string {
+	payload, _ := json.Marshal(historyCursorPayload{
+		Sort:        sort.Field,
+		Ascending:   sort.Ascending,
+		Value:       historySortValue(entry, sort.Field),
+		CompletedAt: entry.CompletedAt.UTC().Format(time.RFC3339Nano),
+		ID:          entry.ID,
+	})
+
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+// decodeHistoryCursor parses a token produced by encodeHistoryCursor.
+// An empty token means "first page" and yields a nil cursor.
+// Tokens issued for a different sort order are rejected.
+func decodeHistoryCursor(token string, sort storage.HistorySort) (*storage.HistoryCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errValidation("invalid cursor")
+	}
+
+	var payload historyCursorPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil, errValidation("invalid cursor")
+	}
+
+	if payload.Sort != sort.Field || payload.Ascending != sort.Ascending {
+		return nil, errValidation("cursor does not match the requested sort order")
+	}
+
+	completedAt, err := time.Parse(time.RFC3339Nano, payload.CompletedAt)
+	if err != nil {
+		return nil, errValidation("invalid cursor")
+	}
+
+	if _, err := uuid.Parse(payload.ID); err != nil {
+		return nil, errValidation("invalid cursor")
+	}
+
+	return &storage.HistoryCursor{Value: payload.Value, CompletedAt: completedAt, ID: payload.ID}, nil
+}
+
+// historySortValue returns the entry's value for a numeric sort field.
+func historySortValue(entry storage.HistoryEntry, field string) int {
+	switch field {
+	case "wpm":
+		return entry.WPM
+	case "accuracy":
+		return entry.Accuracy
+	case "errors":
+		return entry.Errors
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"code-type/backend/internal/http/middleware"
+	"code-type/backend/internal/storage"
+	"code-type/backend/internal/typing"
+)
+
+var historyCSVHeader = []string{
+	"id", "completed_at", "language", "wpm", "accuracy", "errors", "time",
+	"snippet_id", "snippet_hash", "race_id", "verification_status", "created_at",
+}
+
+// historyEncoder writes one export format. begin is called before the first entry
+// and end after the last one, also when there were no entries.
+type historyEncoder interface {
+	begin() error
+	encode(entry historyEntryResponse) error
+	end() error
+}
+
+// handleExportHistory streams the runs matching the list filters as csv, json (an array),
+// or ndjson (one run per line). Rows are written as they are read from the database.
+func (h *HistoryHandler) handleExportHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	filter, sort, err := parseHistoryQuery(r)
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+
+	var (
+		contentType string
+		encoder     historyEncoder
+	)
+	switch format {
+	case "csv":
+		contentType, encoder = "text/csv; charset=utf-8", &csvHistoryEncoder{writer: csv.NewWriter(w)}
+	case "json":
+		contentType, encoder = "application/json", &jsonHistoryEncoder{w: w}
+	case "ndjson":
+		contentType, encoder = "application/x-ndjson", &ndjsonHistoryEncoder{encoder: json.NewEncoder(w)}
+	default:
+		middleware.WriteError(w, http.StatusBadRequest, "format must be one of csv, json, ndjson")
+		return
+	}
+
+	// Headers are sent with the first row, so a query that fails before producing
+	// anything can still be reported as a regular error response.
+	started := false
+	start := func() error {
+		started = true
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="history-%s.%s"`, time.Now().UTC().Format("20060102"), format))
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		return encoder.begin()
+	}
+
+	scan := storage.HistoryScan{UserID: userID, Filter: filter, Sort: sort}
+	err = h.repo.ForEach(r.Context(), scan, func(entry storage.HistoryEntry, _ []typing.Keystroke) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		return encoder.encode(newHistoryEntryResponse(entry))
+	})
+	if err != nil && !started {
+		log.Printf("export history failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to export history")
+		return
+	}
+	if err != nil {
+		// The status line is already out; the truncated body is all the client can notice.
+		log.Printf("export history aborted for user %s: %v", userID, err)
+		return
+	}
+
+	if !started {
+		if err := start(); err != nil {
+			log.Printf("export history aborted for user %s: %v", userID, err)
+			return
+		}
+	}
+
+	if err := encoder.end(); err != nil {
+		log.Printf("export history aborted for user %s: %v", userID, err)
+	}
+}
+
+type csvHistoryEncoder struct {
+	writer *csv.Writer
+}
+
+func (e *csvHistoryEncoder) begin() error {
+	return e.writer.Write(historyCSVHeader)
+}
+
+func (e *csvHistoryEncoder) encode(entry historyEntryResponse) error {
+	return e.writer.Write([]string{
+		entry.ID,
+		entry.CompletedAt,
+		entry.Language,
+		strconv.Itoa(entry.WPM),
+		strconv.Itoa(entry.Accuracy),
+		strconv.Itoa(entry.Errors),
+		strconv.Itoa(entry.Time),
+		entry.SnippetID,
+		entry.SnippetHash,
+		entry.RaceID,
+		entry.VerificationStatus,
+		entry.CreatedAt,
+	})
+}
+
+func (e *csvHistoryEncoder) end() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// jsonHistoryEncoder writes a single JSON array element by element.
+type jsonHistoryEncoder struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonHistoryEncoder) begin() error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonHistoryEncoder) encode(entry historyEntryResponse) error {
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encode history entry: %w", err)
+	}
+
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ","); err != nil {
+			return err
+		}
+	}
+	e.count++
+
+	_, err = e.w.Write(payload)
+	return err
+}
+
+func (e *jsonHistoryEncoder) end() error {
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
+
+type ndjsonHistoryEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonHistoryEncoder) begin() error {
+	return nil
+}
+
+func (e *ndjsonHistoryEncoder) encode(entry historyEntryResponse) error {
+	return e.encoder.Encode(entry)
+}
+
+func (e *ndjsonHistoryEncoder) end() error {
+	return nil
+}
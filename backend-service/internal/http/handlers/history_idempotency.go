@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"code-type/backend/internal/http/middleware"
+	"code-type/backend/internal/storage"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKeyLen = 255
+)
+
+// parseIdempotencyKey reads the optional Idempotency-Key header. Keys are 1 to 255 visible
+// ASCII characters. The request is fingerprinted from its decoded form, so retries that
+// serialize the same run differently (key order, whitespace) still count as the same request.
+// Returns nil when the header is absent.
+func parseIdempotencyKey(r *http.Request, req createHistoryRequest) (*storage.IdempotencyKey, error) {
+	values := r.Header.Values(idempotencyKeyHeader)
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	key := values[0]
+	if len(values) > 1 || key == "" || len(key) > maxIdempotencyKeyLen {
+		return nil, errValidation(fmt.Sprintf("%s must be a single value of 1 to %d characters", idempotencyKeyHeader, maxIdempotencyKeyLen))
+	}
+
+	for i := 0; i < len(key); i++ {
+		if key[i] < '!' || key[i] > '~' {
+			return nil, errValidation(idempotencyKeyHeader + " must contain only visible ASCII characters")
+		}
+	}
+
+	canonical, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("encode history request: %w", err)
+	}
+
+	sum := sha256.Sum256(canonical)
+	return &storage.IdempotencyKey{Key: key, RequestHash: hex.EncodeToString(sum[:])}, nil
+}
+
+// writeHistoryReplay answers a retried request with the entry stored by the original one.
+func writeHistoryReplay(w http.ResponseWriter, entry storage.HistoryEntry) {
+	w.Header().Set("Idempotent-Replayed", "true")
+	writeJSON(w, http.StatusCreated, newHistoryEntryResponse(entry))
+}
+
+// writeIdempotencyError maps Replay and CreateIdempotent errors other than ErrNotFound.
+func writeIdempotencyError(w http.ResponseWriter, userID string, err error) {
+	if errors.Is(err, storage.ErrIdempotencyMismatch) {
+		middleware.WriteError(w, http.StatusConflict, idempotencyKeyHeader+" was already used with a different request")
+		return
+	}
+
+	log.Printf("load idempotency key failed for user %s: %v", userID, err)
+	middleware.WriteError(w, http.StatusInternalServerError, "Failed to save history entry")
+}
@@ -0,0 +1,423 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"code-type/backend/internal/http/middleware"
+	"code-type/backend/internal/storage"
+)
+
+const (
+	maxImportBodyBytes = 16 << 20
+	maxImportRows      = 10000
+)
+
+// Import sources name the layout of the uploaded rows.
+const (
+	importSourceCodeType   = "codetype"
+	importSourceMonkeytype = "monkeytype"
+)
+
+// Row outcomes reported by the import endpoint.
+const (
+	importStatusImported  = "imported"
+	importStatusDuplicate = "duplicate"
+	importStatusInvalid   = "invalid"
+)
+
+var errTooManyImportRows = errValidation(fmt.Sprintf("an import can contain at most %d rows", maxImportRows))
+
+// importFields is one uploaded row keyed by lowercase column or property name.
+// JSON strings are stored unquoted and other JSON values as their literal text.
+type importFields map[string]string
+
+type importRowResponse struct {
+	Row    int    `json:"row"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type importHistoryResponse struct {
+	Total      int                 `json:"total"`
+	Imported   int                 `json:"imported"`
+	Duplicates int                 `json:"duplicates"`
+	Invalid    int                 `json:"invalid"`
+	Rows       []importRowResponse `json:"rows"`
+}
+
+// handleImportHistory bulk-inserts runs exported from this site or another trainer.
+// The body is CSV (text/csv or format=csv) or a JSON array; source selects the column mapping
+// and language supplies a default for rows without one. Every row is validated like a new run,
+// valid rows are inserted in one transaction, and the response reports the outcome of each row.
+func (h *HistoryHandler) handleImportHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	query := r.URL.Query()
+
+	source := query.Get("source")
+	if source == "" {
+		source = importSourceCodeType
+	}
+	if source != importSourceCodeType && source != importSourceMonkeytype {
+		middleware.WriteError(w, http.StatusBadRequest, "source must be codetype or monkeytype")
+		return
+	}
+
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+		if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && mediaType == "text/csv" {
+			format = "csv"
+		}
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxImportBodyBytes)
+
+	var (
+		rows []importFields
+		err  error
+	)
+	switch format {
+	case "csv":
+		rows, err = readImportCSV(body)
+	case "json":
+		rows, err = readImportJSON(body)
+	default:
+		middleware.WriteError(w, http.StatusBadRequest, "format must be csv or json")
+		return
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		middleware.WriteError(w, http.StatusRequestEntityTooLarge, "Import file is too large")
+		return
+	}
+	if errors.Is(err, errTooManyImportRows) {
+		middleware.WriteError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if len(rows) == 0 {
+		middleware.WriteError(w, http.StatusUnprocessableEntity, "import file contains no rows")
+		return
+	}
+
+	languages, err := supportedLanguages(r.Context(), h.snippets)
+	if err != nil {
+		log.Printf("load supported languages failed: %v", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to validate import")
+		return
+	}
+
+	defaultLanguage := strings.ToLower(strings.TrimSpace(query.Get("language")))
+
+	requests := make([]createHistoryRequest, len(rows))
+	mapErrs := make([]error, len(rows))
+	for i, fields := range rows {
+		requests[i], mapErrs[i] = mapImportRow(source, fields, defaultLanguage)
+	}
+
+	// Every snippet the file references is loaded up front, so validating rows does not query per row.
+	snippets := newSnippetLookup(h.snippets)
+	if err := snippets.preload(r.Context(), requests); err != nil {
+		log.Printf("load import snippets failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to validate import")
+		return
+	}
+
+	response := importHistoryResponse{Total: len(rows), Rows: make([]importRowResponse, len(rows))}
+	valid := make([]storage.CreateHistoryParams, 0, len(rows))
+	validRows := make([]int, 0, len(rows))
+
+	for i, req := range requests {
+		response.Rows[i].Row = i + 1
+
+		err := mapErrs[i]
+		if err == nil {
+			var params storage.CreateHistoryParams
+			params, err = h.buildCreateParams(r.Context(), userID, req, languages, snippets)
+			if err == nil {
+				valid = append(valid, params)
+				validRows = append(validRows, i)
+				continue
+			}
+		}
+
+		var validationErr validationError
+		if !errors.As(err, &validationErr) && !errors.Is(err, errInvalidDate) {
+			log.Printf("validate import row %d failed for user %s: %v", i+1, userID, err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to validate import")
+			return
+		}
+
+		response.Rows[i].Status = importStatusInvalid
+		response.Rows[i].Error = err.Error()
+		response.Invalid++
+	}
+
+	var result storage.ImportHistoryResult
+	if len(valid) > 0 {
+		result, err = h.repo.Import(r.Context(), userID, valid)
+		if err != nil {
+			log.Printf("import history failed for user %s: %v", userID, err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to import history")
+			return
+		}
+	}
+
+	for _, i := range validRows {
+		response.Rows[i].Status = importStatusImported
+	}
+	for _, index := range result.Duplicates {
+		response.Rows[validRows[index]].Status = importStatusDuplicate
+	}
+	response.Imported = result.Imported
+	response.Duplicates = len(result.Duplicates)
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// readImportCSV reads a CSV file with a header row, stopping once it holds more rows than an import allows.
+func readImportCSV(body io.Reader) ([]importFields, error) {
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, importReadError("CSV", err)
+	}
+
+	for i, name := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	}
+
+	var rows []importFields
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, importReadError("CSV", err)
+		}
+
+		if len(rows) == maxImportRows {
+			return nil, errTooManyImportRows
+		}
+
+		fields := make(importFields, len(header))
+		for i, value := range record {
+			if i < len(header) {
+				fields[header[i]] = strings.TrimSpace(value)
+			}
+		}
+		rows = append(rows, fields)
+	}
+}
+
+// readImportJSON reads a JSON array of objects, or an object wrapping the array
+// in "items" (our paged list) or "data" (Monkeytype's results export).
+func readImportJSON(body io.Reader) ([]importFields, error) {
+	payload, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []map[string]json.RawMessage
+	if trimmed := bytes.TrimSpace(payload); len(trimmed) > 0 && trimmed[0] == '{' {
+		var wrapper struct {
+			Items []map[string]json.RawMessage `json:"items"`
+			Data  []map[string]json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(trimmed, &wrapper); err != nil {
+			return nil, importReadError("JSON", err)
+		}
+		objects = append(wrapper.Items, wrapper.Data...)
+	} else if err := json.Unmarshal(payload, &objects); err != nil {
+		return nil, importReadError("JSON", err)
+	}
+
+	if len(objects) > maxImportRows {
+		return nil, errTooManyImportRows
+	}
+
+	rows := make([]importFields, len(objects))
+	for i, object := range objects {
+		fields := make(importFields, len(object))
+		for name, raw := range object {
+			var text string
+			if err := json.Unmarshal(raw, &text); err != nil {
+				text = string(raw)
+			}
+			fields[strings.ToLower(name)] = strings.TrimSpace(text)
+		}
+		rows[i] = fields
+	}
+
+	return rows, nil
+}
+
+func importReadError(format string, err error) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return err
+	}
+	return fmt.Errorf("invalid %s file: %v", format, err)
+}
+
+// mapImportRow converts an uploaded row into the payload accepted by POST /history.
+func mapImportRow(source string, fields importFields, defaultLanguage string) (createHistoryRequest, error) {
+	if source == importSourceMonkeytype {
+		return mapMonkeytypeRow(fields, defaultLanguage)
+	}
+
+	req := createHistoryRequest{
+		Language:    strings.ToLower(fields["language"]),
+		SnippetID:   fields["snippet_id"],
+		SnippetHash: fields["snippet_hash"],
+		Date:        fields["date"],
+	}
+	if req.Language == "" {
+		req.Language = defaultLanguage
+	}
+	if req.Date == "" {
+		req.Date = fields["completed_at"]
+	}
+
+	var err error
+	if req.WPM, err = importInt(fields, "wpm"); err != nil {
+		return createHistoryRequest{}, err
+	}
+	if req.Accuracy, err = importInt(fields, "accuracy"); err != nil {
+		return createHistoryRequest{}, err
+	}
+	if req.Errors, err = importInt(fields, "errors"); err != nil {
+		return createHistoryRequest{}, err
+	}
+	if req.Time, err = importInt(fields, "time"); err != nil {
+		return createHistoryRequest{}, err
+	}
+
+	return req, nil
+}
+
+// mapMonkeytypeRow reads a Monkeytype result: wpm, acc and testDuration (seconds) are decimals,
+// timestamp is in Unix milliseconds, and charStats lists correct, incorrect, extra and missed
+// characters, of which all but the first count as errors. Code languages ("code_python")
+// map to ours; prose tests take the default language.
+func mapMonkeytypeRow(fields importFields, defaultLanguage string) (createHistoryRequest, error) {
+	req := createHistoryRequest{Language: defaultLanguage}
+	if language, ok := strings.CutPrefix(strings.ToLower(fields["language"]), "code_"); ok {
+		req.Language = language
+	}
+
+	var err error
+	if req.WPM, err = importRounded(fields, "wpm"); err != nil {
+		return createHistoryRequest{}, err
+	}
+	if req.Accuracy, err = importRounded(fields, "acc"); err != nil {
+		return createHistoryRequest{}, err
+	}
+	if req.Time, err = importRounded(fields, "testduration"); err != nil {
+		return createHistoryRequest{}, err
+	}
+
+	if raw := strings.Trim(fields["charstats"], "[] "); raw != "" {
+		for i, part := range strings.FieldsFunc(raw, func(r rune) bool { return r == ';' || r == ',' }) {
+			count, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || count < 0 {
+				return createHistoryRequest{}, errValidation("charStats must list non-negative character counts")
+			}
+			if i > 0 {
+				req.Errors += count
+			}
+			if req.Errors > math.MaxInt32 {
+				return createHistoryRequest{}, errValidation("charStats counts are too large")
+			}
+		}
+	}
+
+	if raw := fields["timestamp"]; raw != "" {
+		millis, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return createHistoryRequest{}, errValidation("timestamp must be Unix milliseconds")
+		}
+		req.Date = time.UnixMilli(millis).UTC().Format(time.RFC3339)
+	}
+
+	return req, nil
+}
+
+// importInt reads an optional integer field, treating a missing value as zero.
+// Values must fit the INTEGER columns they are stored in.
+func importInt(fields importFields, name string) (int, error) {
+	raw := fields[name]
+	if raw == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, errValidation(name + " must be an integer")
+	}
+
+	if value < math.MinInt32 || value > math.MaxInt32 {
+		return 0, errValidation(name + " is out of range")
+	}
+
+	return value, nil
+}
+
+// importRounded reads an optional decimal field rounded to the nearest integer,
+// with the same range as importInt.
+func importRounded(fields importFields, name string) (int, error) {
+	value, err := importFloat(fields, name)
+	if err != nil {
+		return 0, err
+	}
+
+	value = math.Round(value)
+	if value < math.MinInt32 || value > math.MaxInt32 {
+		return 0, errValidation(name + " is out of range")
+	}
+
+	return int(value), nil
+}
+
+// importFloat reads an optional decimal field, treating a missing value as zero.
+func importFloat(fields importFields, name string) (float64, error) {
+	raw := fields[name]
+	if raw == "" {
+		return 0, nil
+	}
+
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, errValidation(name + " must be a number")
+	}
+
+	return value, nil
+}
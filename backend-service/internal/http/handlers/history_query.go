@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"code-type/backend/internal/storage"
+)
+
+// parseHistoryQuery reads the list filters and sort order shared by history listing endpoints:
+// snippet_id, snippet_hash, language, from, to (RFC3339), min_wpm, max_wpm, min_accuracy,
+// max_accuracy, sort (completed_at|wpm|accuracy|errors) and order (asc|desc).
+func parseHistoryQuery(r *http.Request) (storage.HistoryFilter, storage.HistorySort, error) {
+	query := r.URL.Query()
+
+	snippetID, snippetHash, err := parseSnippetReference(r)
+	if err != nil {
+		return storage.HistoryFilter{}, storage.HistorySort{}, err
+	}
+
+	filter := storage.HistoryFilter{
+		SnippetID:   snippetID,
+		SnippetHash: snippetHash,
+		Language:    strings.ToLower(strings.TrimSpace(query.Get("language"))),
+	}
+
+	if filter.From, err = parseOptionalTime(query.Get("from"), "from"); err != nil {
+		return storage.HistoryFilter{}, storage.HistorySort{}, err
+	}
+
+	if filter.To, err = parseOptionalTime(query.Get("to"), "to"); err != nil {
+		return storage.HistoryFilter{}, storage.HistorySort{}, err
+	}
+
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return storage.HistoryFilter{}, storage.HistorySort{}, errValidation("from must be before to")
+	}
+
+	if filter.MinWPM, err = parseOptionalInt(query.Get("min_wpm"), "min_wpm", 0, -1); err != nil {
+		return storage.HistoryFilter{}, storage.HistorySort{}, err
+	}
+
+	if filter.MaxWPM, err = parseOptionalInt(query.Get("max_wpm"), "max_wpm", 0, -1); err != nil {
+		return storage.HistoryFilter{}, storage.HistorySort{}, err
+	}
+
+	if filter.MinWPM != nil && filter.MaxWPM != nil && *filter.MinWPM > *filter.MaxWPM {
+		return storage.HistoryFilter{}, storage.HistorySort{}, errValidation("min_wpm must not exceed max_wpm")
+	}
+
+	if filter.MinAccuracy, err = parseOptionalInt(query.Get("min_accuracy"), "min_accuracy", 0, 100); err != nil {
+		return storage.HistoryFilter{}, storage.HistorySort{}, err
+	}
+
+	if filter.MaxAccuracy, err = parseOptionalInt(query.Get("max_accuracy"), "max_accuracy", 0, 100); err != nil {
+		return storage.HistoryFilter{}, storage.HistorySort{}, err
+	}
+
+	if filter.MinAccuracy != nil && filter.MaxAccuracy != nil && *filter.MinAccuracy > *filter.MaxAccuracy {
+		return storage.HistoryFilter{}, storage.HistorySort{}, errValidation("min_accuracy must not exceed max_accuracy")
+	}
+
+	sort := storage.HistorySort{Field: query.Get("sort")}
+	if sort.Field == "" {
+		sort.Field = "completed_at"
+	}
+	if !storage.IsHistorySortField(sort.Field) {
+		return storage.HistoryFilter{}, storage.HistorySort{}, errValidation("sort must be one of completed_at, wpm, accuracy, errors")
+	}
+
+	switch query.Get("order") {
+	case "", "desc":
+	case "asc":
+		sort.Ascending = true
+	default:
+		return storage.HistoryFilter{}, storage.HistorySort{}, errValidation("order must be asc or desc")
+	}
+
+	return filter, sort, nil
+}
+
+// parseOptionalTime parses an RFC3339 query value; empty values yield nil.
+func parseOptionalTime(raw, name string) (*time.Time, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	value, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, errValidation(name + " must be an RFC3339 timestamp")
+	}
+
+	return &value, nil
+}
+
+// parseOptionalInt parses an integer query value within [min, max]; a negative max means unbounded.
+// Empty values yield nil.
+func parseOptionalInt(raw, name string, min, max int) (*int, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < min || (max >= 0 && value > max) {
+		if max < 0 {
+			return nil, errValidation(name + " must be a non-negative integer")
+		}
+		return nil, errValidation(name + " must be an integer between " + strconv.Itoa(min) + " and " + strconv.Itoa(max))
+	}
+
+	return &value, nil
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"code-type/backend/internal/http/middleware"
+	"code-type/backend/internal/storage"
+)
+
+type replayResponse struct {
+	historyEntryResponse
+	Snippet    string             `json:"snippet,omitempty"`
+	Keystrokes []keystrokePayload `json:"keystrokes"`
+}
+
+// handleGetReplay returns the keystroke log of a run owned by the caller, together with the run summary
+// and, for catalog snippets, the snippet text so the client can replay it.
+func (h *HistoryHandler) handleGetReplay(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		middleware.WriteError(w, http.StatusNotFound, "Replay not found")
+		return
+	}
+
+	entry, err := h.repo.GetByID(r.Context(), userID, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Replay not found")
+		return
+	}
+	if err != nil {
+		log.Printf("load history entry %s failed: %v", id, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load replay")
+		return
+	}
+
+	keystrokes, err := h.repo.Keystrokes(r.Context(), userID, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Replay not found")
+		return
+	}
+	if err != nil {
+		log.Printf("load keystrokes for %s failed: %v", id, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load replay")
+		return
+	}
+
+	response := replayResponse{
+		historyEntryResponse: newHistoryEntryResponse(entry),
+		Keystrokes:           make([]keystrokePayload, len(keystrokes)),
+	}
+
+	for i, k := range keystrokes {
+		response.Keystrokes[i] = keystrokePayload{C: k.Char, T: k.OffsetMillis, OK: k.Correct}
+	}
+
+	response.Snippet = typedSnippetContent(r.Context(), h.snippets, entry)
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// typedSnippetContent returns the catalog text the run was typed on, or "" when the run has no
+// catalog snippet or the snippet was edited or deleted since.
+func typedSnippetContent(ctx context.Context, snippets *storage.SnippetRepository, entry storage.HistoryEntry) string {
+	if entry.SnippetID == "" {
+		return ""
+	}
+
+	snippet, err := snippets.GetByID(ctx, entry.SnippetID)
+	switch {
+	case err == nil && storage.ContentHash(snippet.Content) == entry.SnippetHash:
+		return snippet.Content
+	case err != nil && !errors.Is(err, storage.ErrNotFound):
+		log.Printf("load snippet %s failed: %v", entry.SnippetID, err)
+	}
+
+	return ""
+}
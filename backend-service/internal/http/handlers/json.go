@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// writeJSON encodes payload as the JSON response body with the given status code.
+// Encoding failures are only logged because the status line has already been sent.
+func writeJSON(w http.ResponseWriter, statusCode int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(payload); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"code-type/backend/internal/http/middleware"
+	"code-type/backend/internal/storage"
+)
+
+const (
+	defaultLeaderboardLimit = 50
+	maxLeaderboardLimit     = 100
+)
+
+// LeaderboardHandler serves the public leaderboards.
+type LeaderboardHandler struct {
+	repo     *storage.LeaderboardRepository
+	snippets *storage.SnippetRepository
+}
+
+// NewLeaderboardHandler creates a new LeaderboardHandler.
+func NewLeaderboardHandler(repo *storage.LeaderboardRepository, snippets *storage.SnippetRepository) *LeaderboardHandler {
+	return &LeaderboardHandler{
+		repo:     repo,
+		snippets: snippets,
+	}
+}
+
+type leaderboardEntryResponse struct {
+	Rank        int    `json:"rank"`
+	DisplayName string `json:"display_name"`
+	HistoryID   string `json:"history_id"`
+	Language    string `json:"language"`
+	WPM         int    `json:"wpm"`
+	Accuracy    int    `json:"accuracy"`
+	CompletedAt string `json:"completed_at"`
+}
+
+type leaderboardResponse struct {
+	Period      string                     `json:"period"`
+	Language    string                     `json:"language,omitempty"`
+	SnippetID   string                     `json:"snippet_id,omitempty"`
+	SnippetHash string                     `json:"snippet_hash,omitempty"`
+	Entries     []leaderboardEntryResponse `json:"entries"`
+}
+
+// GetLeaderboard returns one board of best verified runs. Query parameters: period
+// (daily|weekly|all_time, default all_time), exactly one of language, snippet_id or snippet_hash,
+// limit and offset. Boards are refreshed in the background, so new runs appear with a delay.
+func (h *LeaderboardHandler) GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	period := query.Get("period")
+	if period == "" {
+		period = storage.LeaderboardAllTime
+	}
+	if !storage.IsLeaderboardPeriod(period) {
+		middleware.WriteError(w, http.StatusBadRequest, "period must be one of daily, weekly, all_time")
+		return
+	}
+
+	response := leaderboardResponse{
+		Period:      period,
+		Language:    strings.ToLower(strings.TrimSpace(query.Get("language"))),
+		SnippetID:   query.Get("snippet_id"),
+		SnippetHash: strings.ToLower(query.Get("snippet_hash")),
+	}
+
+	scopes := 0
+	for _, value := range []string{response.Language, response.SnippetID, response.SnippetHash} {
+		if value != "" {
+			scopes++
+		}
+	}
+	if scopes != 1 {
+		middleware.WriteError(w, http.StatusBadRequest, "exactly one of language, snippet_id or snippet_hash is required")
+		return
+	}
+
+	board := storage.LeaderboardQuery{
+		Period: period,
+		Limit:  parseLimit(query.Get("limit"), defaultLeaderboardLimit, maxLeaderboardLimit),
+		Offset: parseOffset(query.Get("offset")),
+	}
+
+	switch {
+	case response.Language != "":
+		board.Scope = storage.LeaderboardScopeLanguage
+		board.Key = response.Language
+	case response.SnippetHash != "":
+		if !snippetHashPattern.MatchString(response.SnippetHash) {
+			middleware.WriteError(w, http.StatusBadRequest, "snippet_hash must be a hex-encoded SHA-256")
+			return
+		}
+		board.Scope = storage.LeaderboardScopeSnippet
+		board.Key = response.SnippetHash
+	default:
+		if _, err := uuid.Parse(response.SnippetID); err != nil {
+			middleware.WriteError(w, http.StatusNotFound, "Snippet not found")
+			return
+		}
+
+		snippet, err := h.snippets.GetByID(r.Context(), response.SnippetID)
+		if errors.Is(err, storage.ErrNotFound) {
+			middleware.WriteError(w, http.StatusNotFound, "Snippet not found")
+			return
+		}
+		if err != nil {
+			log.Printf("load snippet %s failed: %v", response.SnippetID, err)
+			middleware.WriteError(w, http.StatusInternalServerError, "Failed to load leaderboard")
+			return
+		}
+
+		// Runs are ranked by the text that was typed, so a catalog snippet maps to its current hash.
+		board.Scope = storage.LeaderboardScopeSnippet
+		board.Key = storage.ContentHash(snippet.Content)
+		response.SnippetHash = board.Key
+	}
+
+	entries, err := h.repo.List(r.Context(), board)
+	if err != nil {
+		log.Printf("load leaderboard failed: %v", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load leaderboard")
+		return
+	}
+
+	response.Entries = make([]leaderboardEntryResponse, len(entries))
+	for i, entry := range entries {
+		response.Entries[i] = leaderboardEntryResponse{
+			Rank:        entry.Rank,
+			DisplayName: entry.DisplayName,
+			HistoryID:   entry.HistoryID,
+			Language:    entry.Language,
+			WPM:         entry.WPM,
+			Accuracy:    entry.Accuracy,
+			CompletedAt: entry.CompletedAt.Format(time.RFC3339),
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
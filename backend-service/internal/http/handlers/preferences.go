@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"code-type/backend/internal/http/middleware"
+	"code-type/backend/internal/storage"
+)
+
+const maxPreferencesBodyBytes = 16 << 10
+
+// PreferencesHandler serves per-user settings that follow the user across devices.
+type PreferencesHandler struct {
+	repo     *storage.PreferencesRepository
+	snippets *storage.SnippetRepository
+}
+
+// NewPreferencesHandler creates a new PreferencesHandler.
+func NewPreferencesHandler(repo *storage.PreferencesRepository, snippets *storage.SnippetRepository) *PreferencesHandler {
+	return &PreferencesHandler{
+		repo:     repo,
+		snippets: snippets,
+	}
+}
+
+// RegisterRoutes mounts preference routes on the provided router.
+func (h *PreferencesHandler) RegisterRoutes(router chi.Router) {
+	router.Get("/", h.handleGetPreferences)
+	router.Put("/", h.handlePutPreferences)
+}
+
+// preferencesPayload is both the request and the response document.
+// Fields omitted from a PUT are reset to their defaults.
+type preferencesPayload struct {
+	Theme           string `json:"theme"`
+	DefaultLanguage string `json:"default_language"`
+	SnippetLength   string `json:"snippet_length"`
+	ShowLiveStats   bool   `json:"show_live_stats"`
+}
+
+type preferencesResponse struct {
+	preferencesPayload
+	UpdatedAt *string `json:"updated_at"`
+}
+
+func (h *PreferencesHandler) handleGetPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	prefs, err := h.repo.Get(r.Context(), userID)
+	if err != nil {
+		log.Printf("load preferences failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load preferences")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newPreferencesResponse(prefs))
+}
+
+// handlePutPreferences replaces the user's preferences. Unknown fields are rejected
+// so typos do not silently fall back to defaults.
+func (h *PreferencesHandler) handlePutPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	req := preferencesPayload{
+		Theme:         storage.DefaultPreferences.Theme,
+		SnippetLength: storage.DefaultPreferences.SnippetLength,
+		ShowLiveStats: storage.DefaultPreferences.ShowLiveStats,
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPreferencesBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	req.DefaultLanguage = strings.ToLower(strings.TrimSpace(req.DefaultLanguage))
+
+	languages, err := supportedLanguages(r.Context(), h.snippets)
+	if err != nil {
+		log.Printf("load supported languages failed: %v", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to validate preferences")
+		return
+	}
+
+	if err := validatePreferences(req, languages); err != nil {
+		middleware.WriteError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	prefs, err := h.repo.Save(r.Context(), userID, storage.Preferences{
+		Theme:           req.Theme,
+		DefaultLanguage: req.DefaultLanguage,
+		SnippetLength:   req.SnippetLength,
+		ShowLiveStats:   req.ShowLiveStats,
+	})
+	if err != nil {
+		log.Printf("save preferences failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to save preferences")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newPreferencesResponse(prefs))
+}
+
+func validatePreferences(req preferencesPayload, languages map[string]bool) error {
+	switch req.Theme {
+	case "system", "light", "dark":
+	default:
+		return errValidation("theme must be one of system, light, dark")
+	}
+
+	if req.DefaultLanguage != "" && !languages[req.DefaultLanguage] {
+		return errValidation("unsupported default_language")
+	}
+
+	switch req.SnippetLength {
+	case "any", "short", "medium", "long":
+	default:
+		return errValidation("snippet_length must be one of any, short, medium, long")
+	}
+
+	return nil
+}
+
+// newPreferencesResponse reports a null updated_at for defaults that were never saved.
+func newPreferencesResponse(prefs storage.Preferences) preferencesResponse {
+	response := preferencesResponse{
+		preferencesPayload: preferencesPayload{
+			Theme:           prefs.Theme,
+			DefaultLanguage: prefs.DefaultLanguage,
+			SnippetLength:   prefs.SnippetLength,
+			ShowLiveStats:   prefs.ShowLiveStats,
+		},
+	}
+
+	if !prefs.UpdatedAt.IsZero() {
+		updatedAt := prefs.UpdatedAt.Format(time.RFC3339)
+		response.UpdatedAt = &updatedAt
+	}
+
+	return response
+}
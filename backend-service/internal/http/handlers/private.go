@@ -10,12 +10,46 @@ import (
 	"code-type/backend/internal/http/middleware"
 )
 
+// PrivateHandlers groups the handlers mounted under the private API.
+type PrivateHandlers struct {
+	History      *HistoryHandler
+	Account      *AccountHandler
+	Snippets     *SnippetHandler
+	Stats        *StatsHandler
+	Analytics    *AnalyticsHandler
+	Drills       *DrillsHandler
+	Profile      *ProfileHandler
+	Goals        *GoalsHandler
+	Achievements *AchievementsHandler
+	Preferences  *PreferencesHandler
+	Races        *RacesHandler
+	Ghosts       *GhostsHandler
+	Teams        *TeamsHandler
+}
+
 // RegisterPrivateRoutes registers protected endpoints that require authentication.
 // These routes are wrapped with AuthHeaderMiddleware which validates X-User-Id header.
-func RegisterPrivateRoutes(router chi.Router, historyHandler *HistoryHandler, accountHandler *AccountHandler) {
+// Routes under /admin additionally require the caller to be one of adminUserIDs.
+func RegisterPrivateRoutes(router chi.Router, adminUserIDs []string, h PrivateHandlers) {
 	router.Get("/me", handleMe)
-	router.Route("/history", historyHandler.RegisterRoutes)
-	router.Delete("/account", accountHandler.DeleteAccount)
+	router.Route("/history", h.History.RegisterRoutes)
+	router.Route("/profile", h.Profile.RegisterRoutes)
+	router.Route("/goals", h.Goals.RegisterRoutes)
+	router.Route("/preferences", h.Preferences.RegisterRoutes)
+	router.Route("/races", h.Races.RegisterRoutes)
+	router.Route("/teams", h.Teams.RegisterRoutes)
+	router.Get("/ghosts/{history_id}", h.Ghosts.GetGhost)
+	router.Get("/stats", h.Stats.GetStats)
+	router.Get("/analytics/keys", h.Analytics.GetKeys)
+	router.Get("/drills/next", h.Drills.GetNext)
+	router.Get("/achievements", h.Achievements.GetAchievements)
+	router.Delete("/account", h.Account.DeleteAccount)
+	router.Get("/account/export", h.Account.ExportAccount)
+
+	router.Route("/admin", func(admin chi.Router) {
+		admin.Use(middleware.RequireAdmin(adminUserIDs))
+		admin.Route("/snippets", h.Snippets.RegisterAdminRoutes)
+	})
 }
 
 // handleMe returns the authenticated user's ID from request context.
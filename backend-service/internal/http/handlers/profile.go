@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"code-type/backend/internal/http/middleware"
+	"code-type/backend/internal/storage"
+)
+
+// displayNamePattern allows letters, digits, spaces and a few separators; 3 to 32 characters.
+var displayNamePattern = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N} ._-]{1,30}[\p{L}\p{N}]$`)
+
+// ProfileHandler serves the user's public profile settings.
+type ProfileHandler struct {
+	repo *storage.ProfileRepository
+}
+
+// NewProfileHandler creates a new ProfileHandler.
+func NewProfileHandler(repo *storage.ProfileRepository) *ProfileHandler {
+	return &ProfileHandler{repo: repo}
+}
+
+// RegisterRoutes mounts profile routes on the provided router.
+func (h *ProfileHandler) RegisterRoutes(router chi.Router) {
+	router.Get("/", h.handleGetProfile)
+	router.Put("/", h.handlePutProfile)
+}
+
+type profileResponse struct {
+	DisplayName      string `json:"display_name"`
+	LeaderboardOptIn bool   `json:"leaderboard_opt_in"`
+	UpdatedAt        string `json:"updated_at"`
+}
+
+type profileRequest struct {
+	DisplayName      string `json:"display_name"`
+	LeaderboardOptIn bool   `json:"leaderboard_opt_in"`
+}
+
+func (h *ProfileHandler) handleGetProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	profile, err := h.repo.Get(r.Context(), userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Profile not found")
+		return
+	}
+	if err != nil {
+		log.Printf("load profile failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load profile")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newProfileResponse(profile))
+}
+
+func (h *ProfileHandler) handlePutProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req profileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	req.DisplayName = strings.TrimSpace(req.DisplayName)
+	if !displayNamePattern.MatchString(req.DisplayName) {
+		middleware.WriteError(w, http.StatusUnprocessableEntity, "display_name must be 3-32 letters, digits, spaces, dots, dashes or underscores")
+		return
+	}
+
+	profile, err := h.repo.Upsert(r.Context(), userID, storage.ProfileParams{
+		DisplayName:      req.DisplayName,
+		LeaderboardOptIn: req.LeaderboardOptIn,
+	})
+	if errors.Is(err, storage.ErrConflict) {
+		middleware.WriteError(w, http.StatusConflict, "display_name is already taken")
+		return
+	}
+	if err != nil {
+		log.Printf("save profile failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to save profile")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newProfileResponse(profile))
+}
+
+func newProfileResponse(profile storage.Profile) profileResponse {
+	return profileResponse{
+		DisplayName:      profile.DisplayName,
+		LeaderboardOptIn: profile.LeaderboardOptIn,
+		UpdatedAt:        profile.UpdatedAt.Format(time.RFC3339),
+	}
+}
@@ -7,9 +7,12 @@ import (
 )
 
 // RegisterPublicRoutes registers public endpoints accessible without authentication.
-func RegisterPublicRoutes(router chi.Router) {
+func RegisterPublicRoutes(router chi.Router, snippetHandler *SnippetHandler, leaderboardHandler *LeaderboardHandler, accountHandler *AccountHandler) {
 	router.Get("/ping", func(w http.ResponseWriter, _ *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		_, _ = w.Write([]byte(`{"status":"ok"}`))
 	})
+	router.Route("/snippets", snippetHandler.RegisterPublicRoutes)
+	router.Get("/leaderboards", leaderboardHandler.GetLeaderboard)
+	router.Post("/account/restore", accountHandler.RestoreAccount)
 }
@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+
+	"code-type/backend/internal/http/middleware"
+	"code-type/backend/internal/services/races"
+	"code-type/backend/internal/storage"
+)
+
+// RacesHandler serves multiplayer race rooms.
+type RacesHandler struct {
+	service  *races.Service
+	upgrader websocket.Upgrader
+}
+
+// NewRacesHandler creates a new RacesHandler. Browsers may open race sockets only from
+// allowedOrigins; when the list is empty the origin must match the request host.
+func NewRacesHandler(service *races.Service, allowedOrigins []string) *RacesHandler {
+	h := &RacesHandler{service: service}
+	if len(allowedOrigins) > 0 {
+		h.upgrader.CheckOrigin = func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || slices.Contains(allowedOrigins, origin)
+		}
+	}
+
+	return h
+}
+
+// RegisterRoutes mounts race routes on the provided router.
+func (h *RacesHandler) RegisterRoutes(router chi.Router) {
+	router.Post("/", h.handleCreateRace)
+	router.Post("/join", h.handleJoinRace)
+	router.Get("/{id}", h.handleGetRace)
+	router.Get("/{id}/ws", h.handleRaceSocket)
+}
+
+type createRaceRequest struct {
+	SnippetID string `json:"snippet_id"`
+	Language  string `json:"language"`
+}
+
+type joinRaceRequest struct {
+	Code string `json:"code"`
+}
+
+type raceParticipantResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type raceResultResponse struct {
+	Participant     string `json:"participant"`
+	Name            string `json:"name"`
+	HistoryID       string `json:"history_id"`
+	WPM             int    `json:"wpm"`
+	Accuracy        int    `json:"accuracy"`
+	Errors          int    `json:"errors"`
+	DurationSeconds int    `json:"duration_seconds"`
+	CompletedAt     string `json:"completed_at"`
+}
+
+type raceResponse struct {
+	ID           string                    `json:"id"`
+	Code         string                    `json:"code"`
+	Status       string                    `json:"status"`
+	Language     string                    `json:"language"`
+	Instance     string                    `json:"instance"`
+	SnippetID    string                    `json:"snippet_id,omitempty"`
+	SnippetHash  string                    `json:"snippet_hash"`
+	You          string                    `json:"you"`
+	Owner        bool                      `json:"owner"`
+	Participants []raceParticipantResponse `json:"participants"`
+	Results      []raceResultResponse      `json:"results"`
+	CreatedAt    string                    `json:"created_at"`
+	StartedAt    *string                   `json:"started_at"`
+	FinishedAt   *string                   `json:"finished_at"`
+}
+
+// handleCreateRace opens a room on the given catalog snippet, or a random one in language.
+func (h *RacesHandler) handleCreateRace(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req createRaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	req.Language = strings.ToLower(strings.TrimSpace(req.Language))
+	if req.SnippetID == "" && req.Language == "" {
+		middleware.WriteError(w, http.StatusUnprocessableEntity, "snippet_id or language is required")
+		return
+	}
+
+	if req.SnippetID != "" {
+		if _, err := uuid.Parse(req.SnippetID); err != nil {
+			middleware.WriteError(w, http.StatusUnprocessableEntity, "snippet_id must be a UUID")
+			return
+		}
+	}
+
+	details, err := h.service.Create(r.Context(), userID, req.SnippetID, req.Language)
+	if errors.Is(err, races.ErrSnippetNotFound) {
+		middleware.WriteError(w, http.StatusUnprocessableEntity, "No snippet matches the request")
+		return
+	}
+	if err != nil {
+		log.Printf("create race failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create race")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newRaceResponse(details))
+}
+
+func (h *RacesHandler) handleJoinRace(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req joinRaceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	details, err := h.service.Join(r.Context(), userID, req.Code)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		middleware.WriteError(w, http.StatusNotFound, "Race not found")
+	case errors.Is(err, storage.ErrRaceClosed):
+		middleware.WriteError(w, http.StatusConflict, "Race already started, ended or is full")
+	case err != nil:
+		log.Printf("join race failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to join race")
+	default:
+		writeJSON(w, http.StatusOK, newRaceResponse(details))
+	}
+}
+
+func (h *RacesHandler) handleGetRace(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		middleware.WriteError(w, http.StatusNotFound, "Race not found")
+		return
+	}
+
+	details, err := h.service.Get(r.Context(), userID, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Race not found")
+		return
+	}
+	if err != nil {
+		log.Printf("load race %s failed: %v", id, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load race")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newRaceResponse(details))
+}
+
+// handleRaceSocket upgrades a participant's connection and attaches it to the race room.
+// Non-participants get 404; rooms that already ended get 409. Rooms running on another
+// instance get 421 with that instance in X-Race-Instance, so the client can retry through it.
+func (h *RacesHandler) handleRaceSocket(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		middleware.WriteError(w, http.StatusNotFound, "Race not found")
+		return
+	}
+
+	var misrouted races.MisroutedError
+	participant, err := h.service.Participant(r.Context(), userID, id)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		middleware.WriteError(w, http.StatusNotFound, "Race not found")
+		return
+	case errors.Is(err, races.ErrRoomClosed):
+		middleware.WriteError(w, http.StatusConflict, "Race is no longer open")
+		return
+	case errors.As(err, &misrouted):
+		w.Header().Set("X-Race-Instance", misrouted.InstanceID)
+		middleware.WriteError(w, http.StatusMisdirectedRequest, "Race runs on another instance")
+		return
+	case err != nil:
+		log.Printf("load race %s failed: %v", id, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load race")
+		return
+	}
+
+	// Upgrade writes its own error response on failure.
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	if err := h.service.Serve(id, participant, conn); err != nil {
+		_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "race is no longer open"))
+		conn.Close()
+	}
+}
+
+func newRaceResponse(details races.Details) raceResponse {
+	response := raceResponse{
+		ID:           details.Race.ID,
+		Code:         details.Race.Code,
+		Status:       details.Race.Status,
+		Language:     details.Race.Language,
+		Instance:     details.Race.InstanceID,
+		SnippetID:    details.Race.SnippetID,
+		SnippetHash:  details.Race.SnippetHash,
+		You:          details.You,
+		Participants: make([]raceParticipantResponse, len(details.Participants)),
+		Results:      make([]raceResultResponse, len(details.Results)),
+		CreatedAt:    details.Race.CreatedAt.Format(time.RFC3339),
+		StartedAt:    formatOptionalTime(details.Race.StartedAt),
+		FinishedAt:   formatOptionalTime(details.Race.FinishedAt),
+	}
+
+	for i, participant := range details.Participants {
+		response.Participants[i] = raceParticipantResponse{ID: participant.ID, Name: participant.Name}
+		if participant.ID == details.You {
+			response.Owner = participant.UserID == details.Race.OwnerID
+		}
+	}
+
+	for i, result := range details.Results {
+		response.Results[i] = raceResultResponse{
+			Participant:     result.Participant,
+			Name:            result.Name,
+			HistoryID:       result.HistoryID,
+			WPM:             result.WPM,
+			Accuracy:        result.Accuracy,
+			Errors:          result.Errors,
+			DurationSeconds: result.DurationSeconds,
+			CompletedAt:     result.CompletedAt.Format(time.RFC3339),
+		}
+	}
+
+	return response
+}
+
+func formatOptionalTime(value *time.Time) *string {
+	if value == nil {
+		return nil
+	}
+
+	formatted := value.Format(time.RFC3339)
+	return &formatted
+}
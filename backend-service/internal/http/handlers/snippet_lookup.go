@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"code-type/backend/internal/storage"
+)
+
+// snippetLookup resolves the catalog snippets referenced by submitted runs and remembers every
+// answer, including misses, so a request that names the same snippet many times queries it once.
+type snippetLookup struct {
+	repo     *storage.SnippetRepository
+	snippets map[string]*storage.Snippet
+	hashes   map[string]bool
+}
+
+func newSnippetLookup(repo *storage.SnippetRepository) *snippetLookup {
+	return &snippetLookup{
+		repo:     repo,
+		snippets: make(map[string]*storage.Snippet),
+		hashes:   make(map[string]bool),
+	}
+}
+
+// preload resolves the snippet IDs and bare snippet hashes of all requests in two queries.
+// Malformed references are skipped; validation rejects them before they are looked up.
+func (l *snippetLookup) preload(ctx context.Context, requests []createHistoryRequest) error {
+	var ids, hashes []string
+	for _, req := range requests {
+		if id, err := uuid.Parse(req.SnippetID); err == nil {
+			if _, seen := l.snippets[id.String()]; !seen {
+				l.snippets[id.String()] = nil
+				ids = append(ids, id.String())
+			}
+		}
+
+		if _, seen := l.hashes[req.SnippetHash]; req.SnippetID == "" && req.SnippetHash != "" && !seen {
+			l.hashes[req.SnippetHash] = false
+			hashes = append(hashes, req.SnippetHash)
+		}
+	}
+
+	if len(ids) > 0 {
+		snippets, err := l.repo.GetByIDs(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		for i := range snippets {
+			l.snippets[snippets[i].ID] = &snippets[i]
+		}
+	}
+
+	if len(hashes) > 0 {
+		catalog, err := l.repo.CatalogHashes(ctx, hashes)
+		if err != nil {
+			return err
+		}
+
+		for hash := range catalog {
+			l.hashes[hash] = true
+		}
+	}
+
+	return nil
+}
+
+// get returns the catalog snippet with the ID. Returns storage.ErrNotFound if it does not exist.
+func (l *snippetLookup) get(ctx context.Context, id string) (storage.Snippet, error) {
+	// Stored IDs are canonical, so differently cased references share one entry.
+	if parsed, err := uuid.Parse(id); err == nil {
+		id = parsed.String()
+	}
+
+	if snippet, ok := l.snippets[id]; ok {
+		if snippet == nil {
+			return storage.Snippet{}, storage.ErrNotFound
+		}
+		return *snippet, nil
+	}
+
+	snippet, err := l.repo.GetByID(ctx, id)
+	if errors.Is(err, storage.ErrNotFound) {
+		l.snippets[id] = nil
+		return storage.Snippet{}, err
+	}
+	if err != nil {
+		return storage.Snippet{}, fmt.Errorf("load snippet %s: %w", id, err)
+	}
+
+	l.snippets[id] = &snippet
+	return snippet, nil
+}
+
+// isCatalogHash reports whether a catalog snippet has the content hash.
+func (l *snippetLookup) isCatalogHash(ctx context.Context, hash string) (bool, error) {
+	if catalog, ok := l.hashes[hash]; ok {
+		return catalog, nil
+	}
+
+	catalog, err := l.repo.IsCatalogHash(ctx, hash)
+	if err != nil {
+		return false, err
+	}
+
+	l.hashes[hash] = catalog
+	return catalog, nil
+}
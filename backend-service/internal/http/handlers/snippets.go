@@ -0,0 +1,308 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"code-type/backend/internal/http/middleware"
+	"code-type/backend/internal/storage"
+)
+
+const (
+	defaultSnippetLimit = 50
+	maxSnippetLimit     = 200
+
+	maxSnippetTitleLength   = 200
+	maxSnippetContentLength = 20000
+	maxSnippetTags          = 20
+	maxSnippetTagLength     = 32
+)
+
+var languagePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9+#._-]{0,31}$`)
+
+// SnippetHandler provides HTTP handlers for the snippet catalog.
+type SnippetHandler struct {
+	repo *storage.SnippetRepository
+}
+
+// NewSnippetHandler creates a new SnippetHandler.
+func NewSnippetHandler(repo *storage.SnippetRepository) *SnippetHandler {
+	return &SnippetHandler{repo: repo}
+}
+
+// RegisterPublicRoutes mounts read-only catalog routes available without authentication.
+func (h *SnippetHandler) RegisterPublicRoutes(router chi.Router) {
+	router.Get("/", h.handleListSnippets)
+	router.Get("/random", h.handleRandomSnippet)
+	router.Get("/languages", h.handleListLanguages)
+	router.Get("/{id}", h.handleGetSnippet)
+}
+
+// RegisterAdminRoutes mounts catalog management routes. Callers must guard them with RequireAdmin.
+func (h *SnippetHandler) RegisterAdminRoutes(router chi.Router) {
+	router.Post("/", h.handleCreateSnippet)
+	router.Put("/{id}", h.handleUpdateSnippet)
+	router.Delete("/{id}", h.handleDeleteSnippet)
+}
+
+type snippetResponse struct {
+	ID         string   `json:"id"`
+	Language   string   `json:"language"`
+	Title      string   `json:"title"`
+	Difficulty string   `json:"difficulty"`
+	Tags       []string `json:"tags"`
+	Content    string   `json:"content"`
+	CreatedAt  string   `json:"created_at"`
+	UpdatedAt  string   `json:"updated_at"`
+}
+
+type snippetRequest struct {
+	Language   string   `json:"language"`
+	Title      string   `json:"title"`
+	Difficulty string   `json:"difficulty"`
+	Tags       []string `json:"tags"`
+	Content    string   `json:"content"`
+}
+
+func (h *SnippetHandler) handleListSnippets(w http.ResponseWriter, r *http.Request) {
+	filter := parseSnippetFilter(r)
+	filter.Limit = parseLimit(r.URL.Query().Get("limit"), defaultSnippetLimit, maxSnippetLimit)
+	filter.Offset = parseOffset(r.URL.Query().Get("offset"))
+
+	snippets, err := h.repo.List(r.Context(), filter)
+	if err != nil {
+		log.Printf("list snippets failed: %v", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load snippets")
+		return
+	}
+
+	response := make([]snippetResponse, len(snippets))
+	for i, snippet := range snippets {
+		response[i] = newSnippetResponse(snippet)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *SnippetHandler) handleRandomSnippet(w http.ResponseWriter, r *http.Request) {
+	snippet, err := h.repo.Random(r.Context(), parseSnippetFilter(r))
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "No snippet matches the filter")
+		return
+	}
+	if err != nil {
+		log.Printf("pick random snippet failed: %v", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load snippet")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newSnippetResponse(snippet))
+}
+
+func (h *SnippetHandler) handleListLanguages(w http.ResponseWriter, r *http.Request) {
+	languages, err := h.repo.Languages(r.Context())
+	if err != nil {
+		log.Printf("list snippet languages failed: %v", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load languages")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, languages)
+}
+
+func (h *SnippetHandler) handleGetSnippet(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		middleware.WriteError(w, http.StatusNotFound, "Snippet not found")
+		return
+	}
+
+	snippet, err := h.repo.GetByID(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Snippet not found")
+		return
+	}
+	if err != nil {
+		log.Printf("get snippet %s failed: %v", id, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load snippet")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newSnippetResponse(snippet))
+}
+
+func (h *SnippetHandler) handleCreateSnippet(w http.ResponseWriter, r *http.Request) {
+	params, ok := decodeSnippetRequest(w, r)
+	if !ok {
+		return
+	}
+
+	snippet, err := h.repo.Create(r.Context(), params)
+	if err != nil {
+		log.Printf("create snippet failed: %v", err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to save snippet")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newSnippetResponse(snippet))
+}
+
+func (h *SnippetHandler) handleUpdateSnippet(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		middleware.WriteError(w, http.StatusNotFound, "Snippet not found")
+		return
+	}
+
+	params, ok := decodeSnippetRequest(w, r)
+	if !ok {
+		return
+	}
+
+	snippet, err := h.repo.Update(r.Context(), id, params)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Snippet not found")
+		return
+	}
+	if err != nil {
+		log.Printf("update snippet %s failed: %v", id, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to save snippet")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newSnippetResponse(snippet))
+}
+
+func (h *SnippetHandler) handleDeleteSnippet(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if _, err := uuid.Parse(id); err != nil {
+		middleware.WriteError(w, http.StatusNotFound, "Snippet not found")
+		return
+	}
+
+	err := h.repo.Delete(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Snippet not found")
+		return
+	}
+	if err != nil {
+		log.Printf("delete snippet %s failed: %v", id, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete snippet")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeSnippetRequest parses and validates a create/update payload.
+// Writes the error response and returns false when the payload is rejected.
+func decodeSnippetRequest(w http.ResponseWriter, r *http.Request) (storage.SnippetParams, bool) {
+	var req snippetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return storage.SnippetParams{}, false
+	}
+
+	req.Language = strings.ToLower(strings.TrimSpace(req.Language))
+	req.Title = strings.TrimSpace(req.Title)
+	req.Difficulty = strings.ToLower(strings.TrimSpace(req.Difficulty))
+	req.Content = strings.ReplaceAll(req.Content, "\r\n", "\n")
+
+	if err := validateSnippetRequest(req); err != nil {
+		middleware.WriteError(w, http.StatusUnprocessableEntity, err.Error())
+		return storage.SnippetParams{}, false
+	}
+
+	return storage.SnippetParams{
+		Language:   req.Language,
+		Title:      req.Title,
+		Difficulty: req.Difficulty,
+		Tags:       req.Tags,
+		Content:    req.Content,
+	}, true
+}
+
+func validateSnippetRequest(req snippetRequest) error {
+	if !languagePattern.MatchString(req.Language) {
+		return errValidation("language must be a lowercase identifier of up to 32 characters")
+	}
+
+	if req.Title == "" {
+		return errValidation("title is required")
+	}
+
+	if utf8.RuneCountInString(req.Title) > maxSnippetTitleLength {
+		return errValidation("title is too long")
+	}
+
+	if !isSupportedDifficulty(req.Difficulty) {
+		return errValidation("difficulty must be one of easy, medium, hard")
+	}
+
+	if len(req.Tags) > maxSnippetTags {
+		return errValidation("too many tags")
+	}
+
+	for _, tag := range req.Tags {
+		if strings.TrimSpace(tag) == "" || utf8.RuneCountInString(tag) > maxSnippetTagLength {
+			return errValidation("tags must be non-empty and at most 32 characters")
+		}
+	}
+
+	if strings.TrimSpace(req.Content) == "" {
+		return errValidation("content is required")
+	}
+
+	if utf8.RuneCountInString(req.Content) > maxSnippetContentLength {
+		return errValidation("content is too long")
+	}
+
+	return nil
+}
+
+func isSupportedDifficulty(difficulty string) bool {
+	switch difficulty {
+	case "easy", "medium", "hard":
+		return true
+	default:
+		return false
+	}
+}
+
+// parseSnippetFilter reads language, difficulty and comma-separated tags from the query string.
+func parseSnippetFilter(r *http.Request) storage.SnippetFilter {
+	query := r.URL.Query()
+
+	var tags []string
+	if raw := query.Get("tags"); raw != "" {
+		tags = strings.Split(raw, ",")
+	}
+
+	return storage.SnippetFilter{
+		Language:   strings.ToLower(strings.TrimSpace(query.Get("language"))),
+		Difficulty: strings.ToLower(strings.TrimSpace(query.Get("difficulty"))),
+		Tags:       tags,
+	}
+}
+
+func newSnippetResponse(snippet storage.Snippet) snippetResponse {
+	return snippetResponse{
+		ID:         snippet.ID,
+		Language:   snippet.Language,
+		Title:      snippet.Title,
+		Difficulty: snippet.Difficulty,
+		Tags:       snippet.Tags,
+		Content:    snippet.Content,
+		CreatedAt:  snippet.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:  snippet.UpdatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"code-type/backend/internal/http/middleware"
+	"code-type/backend/internal/storage"
+)
+
+const (
+	defaultStatsPeriods = 30
+	maxStatsPeriods     = 366
+)
+
+// StatsHandler serves aggregated practice statistics.
+type StatsHandler struct {
+	repo *storage.HistoryRepository
+}
+
+// NewStatsHandler creates a new StatsHandler.
+func NewStatsHandler(repo *storage.HistoryRepository) *StatsHandler {
+	return &StatsHandler{repo: repo}
+}
+
+type statsResponse struct {
+	Runs            int     `json:"runs"`
+	TimePracticed   int     `json:"time_practiced"`
+	TotalErrors     int     `json:"total_errors"`
+	AverageWPM      float64 `json:"average_wpm"`
+	AverageAccuracy float64 `json:"average_accuracy"`
+	BestWPM         int     `json:"best_wpm"`
+	BestAccuracy    int     `json:"best_accuracy"`
+}
+
+type languageStatsResponse struct {
+	Language string `json:"language"`
+	statsResponse
+}
+
+type periodStatsResponse struct {
+	Start string `json:"start"`
+	statsResponse
+}
+
+type periodsResponse struct {
+	Bucket   string                `json:"bucket"`
+	TimeZone string                `json:"time_zone"`
+	Items    []periodStatsResponse `json:"items"`
+}
+
+type aggregateResponse struct {
+	Totals    statsResponse           `json:"totals"`
+	Languages []languageStatsResponse `json:"languages"`
+	Periods   periodsResponse         `json:"periods"`
+}
+
+// GetStats returns lifetime totals plus per-language and per-period breakdowns.
+// Query parameters: bucket (day|week|month, default day), tz (IANA zone, default UTC)
+// and periods (number of most recent buckets, default 30).
+func (h *StatsHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	query := r.URL.Query()
+
+	bucket := query.Get("bucket")
+	if bucket == "" {
+		bucket = "day"
+	}
+	if !isSupportedBucket(bucket) {
+		middleware.WriteError(w, http.StatusBadRequest, "bucket must be one of day, week, month")
+		return
+	}
+
+	location, err := parseTimeZone(query.Get("tz"))
+	if err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "tz must be a valid IANA time zone")
+		return
+	}
+
+	periods := defaultStatsPeriods
+	if raw := query.Get("periods"); raw != "" {
+		periods, err = strconv.Atoi(raw)
+		if err != nil || periods <= 0 || periods > maxStatsPeriods {
+			middleware.WriteError(w, http.StatusBadRequest, "periods must be between 1 and 366")
+			return
+		}
+	}
+
+	aggregate, err := h.repo.Aggregate(r.Context(), storage.AggregateParams{
+		UserID:   userID,
+		Bucket:   bucket,
+		TimeZone: location.String(),
+		Periods:  periods,
+	})
+	if err != nil {
+		log.Printf("aggregate stats failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load statistics")
+		return
+	}
+
+	response := aggregateResponse{
+		Totals:    newStatsResponse(aggregate.Totals),
+		Languages: make([]languageStatsResponse, len(aggregate.Languages)),
+		Periods: periodsResponse{
+			Bucket:   bucket,
+			TimeZone: location.String(),
+			Items:    make([]periodStatsResponse, len(aggregate.Periods)),
+		},
+	}
+
+	for i, language := range aggregate.Languages {
+		response.Languages[i] = languageStatsResponse{
+			Language:      language.Language,
+			statsResponse: newStatsResponse(language.HistoryStats),
+		}
+	}
+
+	for i, period := range aggregate.Periods {
+		response.Periods.Items[i] = periodStatsResponse{
+			Start:         period.Start.In(location).Format(time.RFC3339),
+			statsResponse: newStatsResponse(period.HistoryStats),
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func isSupportedBucket(bucket string) bool {
+	switch bucket {
+	case "day", "week", "month":
+		return true
+	default:
+		return false
+	}
+}
+
+// parseTimeZone resolves an IANA zone name, defaulting to UTC when empty.
+// "Local" is rejected because it refers to the server's zone, which PostgreSQL cannot resolve.
+func parseTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+
+	if name == "Local" {
+		return nil, errValidation("unknown time zone")
+	}
+
+	return time.LoadLocation(name)
+}
+
+func newStatsResponse(stats storage.HistoryStats) statsResponse {
+	return statsResponse{
+		Runs:            stats.Runs,
+		TimePracticed:   stats.TotalSeconds,
+		TotalErrors:     stats.TotalErrors,
+		AverageWPM:      stats.AverageWPM,
+		AverageAccuracy: stats.AverageAccuracy,
+		BestWPM:         stats.BestWPM,
+		BestAccuracy:    stats.BestAccuracy,
+	}
+}
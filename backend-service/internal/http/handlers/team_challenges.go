@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"code-type/backend/internal/http/middleware"
+	"code-type/backend/internal/storage"
+)
+
+const (
+	maxChallengeSnippets = 10
+	maxChallengeDuration = 90 * 24 * time.Hour
+)
+
+type createChallengeRequest struct {
+	Name       string    `json:"name"`
+	SnippetIDs []string  `json:"snippet_ids"`
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+}
+
+type challengeSnippetResponse struct {
+	SnippetID   string `json:"snippet_id,omitempty"`
+	SnippetHash string `json:"snippet_hash"`
+	Language    string `json:"language"`
+}
+
+type challengeStandingResponse struct {
+	Rank              int     `json:"rank"`
+	Member            string  `json:"member"`
+	Name              string  `json:"name"`
+	You               bool    `json:"you"`
+	Score             int     `json:"score"`
+	SnippetsCompleted int     `json:"snippets_completed"`
+	AverageAccuracy   float64 `json:"average_accuracy"`
+	LastCompletedAt   string  `json:"last_completed_at"`
+}
+
+// challengeResponse lists snippets only when they were loaded; challenge lists omit them.
+type challengeResponse struct {
+	ID        string                     `json:"id"`
+	Name      string                     `json:"name"`
+	Status    string                     `json:"status"`
+	StartsAt  string                     `json:"starts_at"`
+	EndsAt    string                     `json:"ends_at"`
+	CreatedAt string                     `json:"created_at"`
+	OpenedAt  *string                    `json:"opened_at"`
+	ClosedAt  *string                    `json:"closed_at"`
+	Snippets  []challengeSnippetResponse `json:"snippets,omitempty"`
+}
+
+// challengeDetailResponse adds the ranking; Final is true once the standings are frozen.
+type challengeDetailResponse struct {
+	challengeResponse
+	Final     bool                        `json:"final"`
+	Standings []challengeStandingResponse `json:"standings"`
+}
+
+func (h *TeamsHandler) handleListChallenges(w http.ResponseWriter, r *http.Request) {
+	teamID := chi.URLParam(r, "team_id")
+
+	challenges, err := h.challenges.ListByTeam(r.Context(), teamID)
+	if err != nil {
+		log.Printf("list challenges of team %s failed: %v", teamID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load challenges")
+		return
+	}
+
+	response := make([]challengeResponse, len(challenges))
+	for i, challenge := range challenges {
+		response[i] = newChallengeResponse(challenge)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleCreateChallenge schedules a challenge on catalog snippets. Runs typed on those snippets
+// between starts_at and ends_at count toward it; the scheduler opens and closes it.
+func (h *TeamsHandler) handleCreateChallenge(w http.ResponseWriter, r *http.Request) {
+	teamID := chi.URLParam(r, "team_id")
+
+	var req createChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	params, err := h.buildChallengeParams(r, teamID, req)
+	var validationErr validationError
+	if errors.As(err, &validationErr) {
+		middleware.WriteError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("validate challenge for team %s failed: %v", teamID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create challenge")
+		return
+	}
+
+	challenge, err := h.challenges.Create(r.Context(), params)
+	if err != nil {
+		log.Printf("create challenge for team %s failed: %v", teamID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create challenge")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newChallengeResponse(challenge))
+}
+
+// handleGetChallenge returns a challenge with its standings: live while it runs,
+// and the standings frozen by the scheduler once it has closed.
+func (h *TeamsHandler) handleGetChallenge(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	teamID := chi.URLParam(r, "team_id")
+
+	challenge, ok := h.loadChallenge(w, r, teamID)
+	if !ok {
+		return
+	}
+
+	standings, err := h.challenges.Standings(r.Context(), challenge)
+	if err != nil {
+		log.Printf("load standings of challenge %s failed: %v", challenge.ID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load challenge")
+		return
+	}
+
+	response := challengeDetailResponse{
+		challengeResponse: newChallengeResponse(challenge),
+		Final:             challenge.Status == storage.ChallengeClosed,
+		Standings:         make([]challengeStandingResponse, len(standings)),
+	}
+	for i, standing := range standings {
+		response.Standings[i] = challengeStandingResponse{
+			Rank:              standing.Rank,
+			Member:            standing.MemberID,
+			Name:              standing.DisplayName,
+			You:               standing.UserID == userID,
+			Score:             standing.Score,
+			SnippetsCompleted: standing.SnippetsCompleted,
+			AverageAccuracy:   standing.AverageAccuracy,
+			LastCompletedAt:   standing.LastCompletedAt.Format(time.RFC3339),
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *TeamsHandler) handleDeleteChallenge(w http.ResponseWriter, r *http.Request) {
+	teamID := chi.URLParam(r, "team_id")
+
+	challengeID := chi.URLParam(r, "challenge_id")
+	if _, err := uuid.Parse(challengeID); err != nil {
+		middleware.WriteError(w, http.StatusNotFound, "Challenge not found")
+		return
+	}
+
+	err := h.challenges.Delete(r.Context(), teamID, challengeID)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Challenge not found")
+		return
+	}
+	if err != nil {
+		log.Printf("delete challenge %s failed: %v", challengeID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete challenge")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// buildChallengeParams validates a challenge request and resolves its snippets.
+// Client errors are returned as validationError values.
+func (h *TeamsHandler) buildChallengeParams(r *http.Request, teamID string, req createChallengeRequest) (storage.CreateChallengeParams, error) {
+	name, err := normalizeTeamName(req.Name)
+	if err != nil {
+		return storage.CreateChallengeParams{}, err
+	}
+
+	if req.StartsAt.IsZero() || req.EndsAt.IsZero() {
+		return storage.CreateChallengeParams{}, errValidation("starts_at and ends_at are required RFC3339 timestamps")
+	}
+
+	if !req.EndsAt.After(req.StartsAt) {
+		return storage.CreateChallengeParams{}, errValidation("ends_at must be after starts_at")
+	}
+
+	if !req.EndsAt.After(time.Now()) {
+		return storage.CreateChallengeParams{}, errValidation("ends_at must be in the future")
+	}
+
+	if req.EndsAt.Sub(req.StartsAt) > maxChallengeDuration {
+		return storage.CreateChallengeParams{}, errValidation("a challenge can last at most 90 days")
+	}
+
+	if len(req.SnippetIDs) == 0 || len(req.SnippetIDs) > maxChallengeSnippets {
+		return storage.CreateChallengeParams{}, errValidation(fmt.Sprintf("snippet_ids must list between 1 and %d snippets", maxChallengeSnippets))
+	}
+
+	params := storage.CreateChallengeParams{
+		TeamID:   teamID,
+		Name:     name,
+		StartsAt: req.StartsAt,
+		EndsAt:   req.EndsAt,
+		Snippets: make([]storage.ChallengeSnippet, 0, len(req.SnippetIDs)),
+	}
+
+	// Runs are matched by the text that was typed, so each catalog snippet maps to its current hash.
+	seen := make(map[string]bool, len(req.SnippetIDs))
+	for _, id := range req.SnippetIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return storage.CreateChallengeParams{}, errValidation("snippet_ids must be UUIDs")
+		}
+
+		snippet, err := h.snippets.GetByID(r.Context(), id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return storage.CreateChallengeParams{}, errValidation("unknown snippet " + id)
+		}
+		if err != nil {
+			return storage.CreateChallengeParams{}, fmt.Errorf("load snippet %s: %w", id, err)
+		}
+
+		hash := storage.ContentHash(snippet.Content)
+		if seen[hash] {
+			return storage.CreateChallengeParams{}, errValidation("duplicate snippet " + id)
+		}
+		seen[hash] = true
+
+		params.Snippets = append(params.Snippets, storage.ChallengeSnippet{
+			SnippetID:   snippet.ID,
+			SnippetHash: hash,
+			Language:    snippet.Language,
+		})
+	}
+
+	return params, nil
+}
+
+// loadChallenge resolves the {challenge_id} route parameter within the team, writing 404 when it does not exist.
+func (h *TeamsHandler) loadChallenge(w http.ResponseWriter, r *http.Request, teamID string) (storage.Challenge, bool) {
+	challengeID := chi.URLParam(r, "challenge_id")
+	if _, err := uuid.Parse(challengeID); err != nil {
+		middleware.WriteError(w, http.StatusNotFound, "Challenge not found")
+		return storage.Challenge{}, false
+	}
+
+	challenge, err := h.challenges.Get(r.Context(), teamID, challengeID)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Challenge not found")
+		return storage.Challenge{}, false
+	}
+	if err != nil {
+		log.Printf("load challenge %s failed: %v", challengeID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load challenge")
+		return storage.Challenge{}, false
+	}
+
+	return challenge, true
+}
+
+func newChallengeResponse(challenge storage.Challenge) challengeResponse {
+	response := challengeResponse{
+		ID:        challenge.ID,
+		Name:      challenge.Name,
+		Status:    challenge.Status,
+		StartsAt:  challenge.StartsAt.Format(time.RFC3339),
+		EndsAt:    challenge.EndsAt.Format(time.RFC3339),
+		CreatedAt: challenge.CreatedAt.Format(time.RFC3339),
+		OpenedAt:  formatOptionalTime(challenge.OpenedAt),
+		ClosedAt:  formatOptionalTime(challenge.ClosedAt),
+	}
+
+	for _, snippet := range challenge.Snippets {
+		response.Snippets = append(response.Snippets, challengeSnippetResponse{
+			SnippetID:   snippet.SnippetID,
+			SnippetHash: snippet.SnippetHash,
+			Language:    snippet.Language,
+		})
+	}
+
+	return response
+}
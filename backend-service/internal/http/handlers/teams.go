@@ -0,0 +1,570 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"code-type/backend/internal/http/middleware"
+	"code-type/backend/internal/joincode"
+	"code-type/backend/internal/storage"
+)
+
+const (
+	inviteLength   = 8
+	inviteAttempts = 5
+	maxTeamName    = 64
+)
+
+// TeamsHandler serves teams, their members, shared dashboards and challenges.
+type TeamsHandler struct {
+	repo       *storage.TeamRepository
+	challenges *storage.ChallengeRepository
+	snippets   *storage.SnippetRepository
+}
+
+// NewTeamsHandler creates a new TeamsHandler.
+func NewTeamsHandler(repo *storage.TeamRepository, challenges *storage.ChallengeRepository, snippets *storage.SnippetRepository) *TeamsHandler {
+	return &TeamsHandler{
+		repo:       repo,
+		challenges: challenges,
+		snippets:   snippets,
+	}
+}
+
+// RegisterRoutes mounts team routes on the provided router.
+// Routes of a single team are guarded by the caller's role in it.
+func (h *TeamsHandler) RegisterRoutes(router chi.Router) {
+	router.Get("/", h.handleListTeams)
+	router.Post("/", h.handleCreateTeam)
+	router.Post("/join", h.handleJoinTeam)
+
+	member := middleware.RequireTeamRole(h.repo, storage.TeamRoleMember)
+	admin := middleware.RequireTeamRole(h.repo, storage.TeamRoleAdmin)
+	owner := middleware.RequireTeamRole(h.repo, storage.TeamRoleOwner)
+
+	router.Route("/{team_id}", func(team chi.Router) {
+		team.With(member).Get("/", h.handleGetTeam)
+		team.With(owner).Delete("/", h.handleDeleteTeam)
+		team.With(member).Post("/leave", h.handleLeaveTeam)
+		team.With(member).Get("/members", h.handleListMembers)
+		team.With(owner).Put("/members/{member_id}", h.handleSetMemberRole)
+		team.With(admin).Delete("/members/{member_id}", h.handleRemoveMember)
+		team.With(admin).Post("/invite-code", h.handleRotateInviteCode)
+		team.With(member).Get("/dashboard", h.handleGetDashboard)
+		team.With(member).Get("/leaderboard", h.handleGetLeaderboard)
+		team.With(member).Get("/challenges", h.handleListChallenges)
+		team.With(admin).Post("/challenges", h.handleCreateChallenge)
+		team.With(member).Get("/challenges/{challenge_id}", h.handleGetChallenge)
+		team.With(admin).Delete("/challenges/{challenge_id}", h.handleDeleteChallenge)
+	})
+}
+
+type createTeamRequest struct {
+	Name string `json:"name"`
+}
+
+type joinTeamRequest struct {
+	Code string `json:"code"`
+}
+
+type setMemberRoleRequest struct {
+	Role string `json:"role"`
+}
+
+// teamResponse shows the invite code only to admins and the owner.
+type teamResponse struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Role        string `json:"role"`
+	MemberCount int    `json:"member_count"`
+	InviteCode  string `json:"invite_code,omitempty"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// teamMemberResponse identifies members by membership ID and display name, never by identity ID.
+type teamMemberResponse struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	You      bool   `json:"you"`
+	JoinedAt string `json:"joined_at"`
+}
+
+type teamMemberStatsResponse struct {
+	teamMemberResponse
+	statsResponse
+}
+
+type teamDashboardResponse struct {
+	Period  string                    `json:"period"`
+	Totals  statsResponse             `json:"totals"`
+	Members []teamMemberStatsResponse `json:"members"`
+}
+
+type teamLeaderboardEntryResponse struct {
+	Rank        int    `json:"rank"`
+	Member      string `json:"member"`
+	Name        string `json:"name"`
+	You         bool   `json:"you"`
+	HistoryID   string `json:"history_id"`
+	Language    string `json:"language"`
+	WPM         int    `json:"wpm"`
+	Accuracy    int    `json:"accuracy"`
+	CompletedAt string `json:"completed_at"`
+}
+
+type teamLeaderboardResponse struct {
+	Period   string                         `json:"period"`
+	Language string                         `json:"language,omitempty"`
+	Entries  []teamLeaderboardEntryResponse `json:"entries"`
+}
+
+func (h *TeamsHandler) handleListTeams(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	teams, err := h.repo.ListByUser(r.Context(), userID)
+	if err != nil {
+		log.Printf("list teams failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load teams")
+		return
+	}
+
+	response := make([]teamResponse, len(teams))
+	for i, team := range teams {
+		response[i] = newTeamResponse(team)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleCreateTeam creates a team owned by the caller with a fresh invite code.
+func (h *TeamsHandler) handleCreateTeam(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req createTeamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	name, err := normalizeTeamName(req.Name)
+	if err != nil {
+		middleware.WriteError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	params := storage.CreateTeamParams{Name: name, OwnerID: userID}
+
+	var team storage.TeamMembership
+	for attempt := 0; ; attempt++ {
+		if params.InviteCode, err = joincode.New(inviteLength); err != nil {
+			break
+		}
+
+		team, err = h.repo.Create(r.Context(), params)
+		if errors.Is(err, storage.ErrConflict) && attempt < inviteAttempts {
+			continue
+		}
+		break
+	}
+	if err != nil {
+		log.Printf("create team failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to create team")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newTeamResponse(team))
+}
+
+func (h *TeamsHandler) handleJoinTeam(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserIDFromContext(r.Context())
+	if !ok || userID == "" {
+		middleware.WriteError(w, http.StatusUnauthorized, "User not authenticated")
+		return
+	}
+
+	var req joinTeamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	teamID, err := h.repo.Join(r.Context(), strings.ToUpper(strings.TrimSpace(req.Code)), userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Team not found")
+		return
+	}
+	if err != nil {
+		log.Printf("join team failed for user %s: %v", userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to join team")
+		return
+	}
+
+	h.writeTeam(w, r, teamID, userID, http.StatusOK)
+}
+
+func (h *TeamsHandler) handleGetTeam(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	h.writeTeam(w, r, chi.URLParam(r, "team_id"), userID, http.StatusOK)
+}
+
+func (h *TeamsHandler) handleDeleteTeam(w http.ResponseWriter, r *http.Request) {
+	teamID := chi.URLParam(r, "team_id")
+
+	err := h.repo.Delete(r.Context(), teamID)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Team not found")
+		return
+	}
+	if err != nil {
+		log.Printf("delete team %s failed: %v", teamID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to delete team")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleLeaveTeam removes the caller from the team. An owner who leaves hands the team over
+// to the longest-standing admin or member.
+func (h *TeamsHandler) handleLeaveTeam(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	teamID := chi.URLParam(r, "team_id")
+
+	err := h.repo.Leave(r.Context(), teamID, userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Team not found")
+		return
+	}
+	if err != nil {
+		log.Printf("leave team %s failed for user %s: %v", teamID, userID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to leave team")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TeamsHandler) handleListMembers(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	teamID := chi.URLParam(r, "team_id")
+
+	members, err := h.repo.Members(r.Context(), teamID)
+	if err != nil {
+		log.Printf("list members of team %s failed: %v", teamID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load team members")
+		return
+	}
+
+	response := make([]teamMemberResponse, len(members))
+	for i, member := range members {
+		response[i] = newTeamMemberResponse(member, userID)
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleSetMemberRole promotes a member to admin or demotes an admin. The owner's role is fixed.
+func (h *TeamsHandler) handleSetMemberRole(w http.ResponseWriter, r *http.Request) {
+	teamID := chi.URLParam(r, "team_id")
+
+	member, ok := h.loadMember(w, r, teamID)
+	if !ok {
+		return
+	}
+
+	var req setMemberRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		middleware.WriteError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if req.Role != storage.TeamRoleAdmin && req.Role != storage.TeamRoleMember {
+		middleware.WriteError(w, http.StatusUnprocessableEntity, "role must be one of admin, member")
+		return
+	}
+
+	if member.Role == storage.TeamRoleOwner {
+		middleware.WriteError(w, http.StatusConflict, "The owner's role cannot be changed")
+		return
+	}
+
+	err := h.repo.SetRole(r.Context(), teamID, member.ID, req.Role)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Member not found")
+		return
+	}
+	if err != nil {
+		log.Printf("set role of member %s failed: %v", member.ID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to update member")
+		return
+	}
+
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	member.Role = req.Role
+	writeJSON(w, http.StatusOK, newTeamMemberResponse(member, userID))
+}
+
+// handleRemoveMember removes someone from the team. Admins may remove members;
+// the owner may also remove admins. Nobody can remove the owner.
+func (h *TeamsHandler) handleRemoveMember(w http.ResponseWriter, r *http.Request) {
+	teamID := chi.URLParam(r, "team_id")
+	actorRole, _ := middleware.TeamRoleFromContext(r.Context())
+
+	member, ok := h.loadMember(w, r, teamID)
+	if !ok {
+		return
+	}
+
+	if member.Role != storage.TeamRoleMember && !(member.Role == storage.TeamRoleAdmin && actorRole == storage.TeamRoleOwner) {
+		middleware.WriteError(w, http.StatusForbidden, "Not allowed to remove this member")
+		return
+	}
+
+	err := h.repo.RemoveMember(r.Context(), teamID, member.ID, actorRole)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Member not found")
+		return
+	}
+	if err != nil {
+		log.Printf("remove member %s failed: %v", member.ID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to remove member")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleRotateInviteCode replaces the invite code; the previous code stops working immediately.
+func (h *TeamsHandler) handleRotateInviteCode(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	teamID := chi.URLParam(r, "team_id")
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		var code string
+		if code, err = joincode.New(inviteLength); err != nil {
+			break
+		}
+
+		err = h.repo.RotateInviteCode(r.Context(), teamID, code)
+		if errors.Is(err, storage.ErrConflict) && attempt < inviteAttempts {
+			continue
+		}
+		break
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Team not found")
+		return
+	}
+	if err != nil {
+		log.Printf("rotate invite code of team %s failed: %v", teamID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to rotate invite code")
+		return
+	}
+
+	h.writeTeam(w, r, teamID, userID, http.StatusOK)
+}
+
+// handleGetDashboard returns team totals and per-member aggregates over all runs in the period.
+// Query parameters: period (daily|weekly|all_time, default weekly), in UTC windows like the public boards.
+func (h *TeamsHandler) handleGetDashboard(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	teamID := chi.URLParam(r, "team_id")
+
+	period, ok := parseTeamPeriod(w, r)
+	if !ok {
+		return
+	}
+
+	dashboard, err := h.repo.Dashboard(r.Context(), teamID, period)
+	if err != nil {
+		log.Printf("load dashboard of team %s failed: %v", teamID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load team dashboard")
+		return
+	}
+
+	response := teamDashboardResponse{
+		Period:  period,
+		Totals:  newStatsResponse(dashboard.Totals),
+		Members: make([]teamMemberStatsResponse, len(dashboard.Members)),
+	}
+
+	for i, member := range dashboard.Members {
+		response.Members[i] = teamMemberStatsResponse{
+			teamMemberResponse: newTeamMemberResponse(member.TeamMember, userID),
+			statsResponse:      newStatsResponse(member.HistoryStats),
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetLeaderboard ranks members by their best verified run in the period.
+// Query parameters: period (daily|weekly|all_time, default weekly) and optional language.
+func (h *TeamsHandler) handleGetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.UserIDFromContext(r.Context())
+	teamID := chi.URLParam(r, "team_id")
+
+	period, ok := parseTeamPeriod(w, r)
+	if !ok {
+		return
+	}
+
+	language := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("language")))
+	if language != "" && !languagePattern.MatchString(language) {
+		middleware.WriteError(w, http.StatusBadRequest, "language must be a lowercase identifier of up to 32 characters")
+		return
+	}
+
+	entries, err := h.repo.Leaderboard(r.Context(), storage.TeamLeaderboardQuery{
+		TeamID:   teamID,
+		Period:   period,
+		Language: language,
+	})
+	if err != nil {
+		log.Printf("load leaderboard of team %s failed: %v", teamID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load team leaderboard")
+		return
+	}
+
+	response := teamLeaderboardResponse{
+		Period:   period,
+		Language: language,
+		Entries:  make([]teamLeaderboardEntryResponse, len(entries)),
+	}
+
+	for i, entry := range entries {
+		response.Entries[i] = teamLeaderboardEntryResponse{
+			Rank:        entry.Rank,
+			Member:      entry.Member.ID,
+			Name:        teamMemberName(entry.Member),
+			You:         entry.Member.UserID == userID,
+			HistoryID:   entry.HistoryID,
+			Language:    entry.Language,
+			WPM:         entry.WPM,
+			Accuracy:    entry.Accuracy,
+			CompletedAt: entry.CompletedAt.Format(time.RFC3339),
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *TeamsHandler) writeTeam(w http.ResponseWriter, r *http.Request, teamID, userID string, statusCode int) {
+	team, err := h.repo.Get(r.Context(), teamID, userID)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Team not found")
+		return
+	}
+	if err != nil {
+		log.Printf("load team %s failed: %v", teamID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load team")
+		return
+	}
+
+	writeJSON(w, statusCode, newTeamResponse(team))
+}
+
+// loadMember resolves the {member_id} route parameter within the team, writing 404 when it does not exist.
+func (h *TeamsHandler) loadMember(w http.ResponseWriter, r *http.Request, teamID string) (storage.TeamMember, bool) {
+	memberID := chi.URLParam(r, "member_id")
+	if _, err := uuid.Parse(memberID); err != nil {
+		middleware.WriteError(w, http.StatusNotFound, "Member not found")
+		return storage.TeamMember{}, false
+	}
+
+	member, err := h.repo.Member(r.Context(), teamID, memberID)
+	if errors.Is(err, storage.ErrNotFound) {
+		middleware.WriteError(w, http.StatusNotFound, "Member not found")
+		return storage.TeamMember{}, false
+	}
+	if err != nil {
+		log.Printf("load member %s failed: %v", memberID, err)
+		middleware.WriteError(w, http.StatusInternalServerError, "Failed to load member")
+		return storage.TeamMember{}, false
+	}
+
+	return member, true
+}
+
+func parseTeamPeriod(w http.ResponseWriter, r *http.Request) (string, bool) {
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		return storage.LeaderboardWeekly, true
+	}
+
+	if !storage.IsLeaderboardPeriod(period) {
+		middleware.WriteError(w, http.StatusBadRequest, "period must be one of daily, weekly, all_time")
+		return "", false
+	}
+
+	return period, true
+}
+
+func normalizeTeamName(raw string) (string, error) {
+	name := strings.TrimSpace(raw)
+	if length := utf8.RuneCountInString(name); length < 2 || length > maxTeamName {
+		return "", errValidation(fmt.Sprintf("name must be between 2 and %d characters", maxTeamName))
+	}
+
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return "", errValidation("name must not contain control characters")
+	}
+
+	return name, nil
+}
+
+func newTeamResponse(team storage.TeamMembership) teamResponse {
+	response := teamResponse{
+		ID:          team.ID,
+		Name:        team.Name,
+		Role:        team.Role,
+		MemberCount: team.MemberCount,
+		CreatedAt:   team.CreatedAt.Format(time.RFC3339),
+	}
+
+	if storage.TeamRoleAtLeast(team.Role, storage.TeamRoleAdmin) {
+		response.InviteCode = team.InviteCode
+	}
+
+	return response
+}
+
+func newTeamMemberResponse(member storage.TeamMember, userID string) teamMemberResponse {
+	return teamMemberResponse{
+		ID:       member.ID,
+		Name:     teamMemberName(member),
+		Role:     member.Role,
+		You:      member.UserID == userID,
+		JoinedAt: member.JoinedAt.Format(time.RFC3339),
+	}
+}
+
+// teamMemberName falls back to the member's join position for users without a profile.
+func teamMemberName(member storage.TeamMember) string {
+	if member.DisplayName != "" {
+		return member.DisplayName
+	}
+
+	return "Member " + strconv.Itoa(member.Ordinal)
+}
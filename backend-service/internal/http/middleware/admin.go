@@ -0,0 +1,30 @@
+package middleware
+
+import "net/http"
+
+// RequireAdmin restricts access to the configured administrator identities.
+// Must be mounted after AuthHeaderMiddleware so the user ID is available in context.
+// Non-admin users receive 403 Forbidden.
+func RequireAdmin(adminUserIDs []string) func(http.Handler) http.Handler {
+	admins := make(map[string]bool, len(adminUserIDs))
+	for _, id := range adminUserIDs {
+		admins[id] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := UserIDFromContext(r.Context())
+			if !ok || userID == "" {
+				WriteError(w, http.StatusUnauthorized, "User not authenticated")
+				return
+			}
+
+			if !admins[userID] {
+				WriteError(w, http.StatusForbidden, "Administrator access required")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -62,6 +62,8 @@ func getErrorCode(statusCode int) string {
 		return "NOT_FOUND"
 	case statusCode == 400:
 		return "BAD_REQUEST"
+	case statusCode == 409:
+		return "CONFLICT"
 	case statusCode == 422:
 		return "VALIDATION_ERROR"
 	default:
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"code-type/backend/internal/storage"
+)
+
+// teamRoleKey is the context key for the caller's role in the team named by the route.
+type teamRoleKey struct{}
+
+// TeamRoles looks up a user's role in a team, returning storage.ErrNotFound for non-members.
+type TeamRoles interface {
+	Role(ctx context.Context, teamID, userID string) (string, error)
+}
+
+// TeamRoleFromContext returns the caller's role stored by RequireTeamRole.
+func TeamRoleFromContext(ctx context.Context) (string, bool) {
+	value, ok := ctx.Value(teamRoleKey{}).(string)
+	return value, ok
+}
+
+// RequireTeamRole restricts a route with a {team_id} parameter to team members holding at least minimum.
+// Must be mounted after AuthHeaderMiddleware so the user ID is available in context.
+// Non-members receive 404 Not Found so team IDs cannot be probed; members with a lesser role receive 403 Forbidden.
+func RequireTeamRole(roles TeamRoles, minimum string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := UserIDFromContext(r.Context())
+			if !ok || userID == "" {
+				WriteError(w, http.StatusUnauthorized, "User not authenticated")
+				return
+			}
+
+			teamID := chi.URLParam(r, "team_id")
+			if _, err := uuid.Parse(teamID); err != nil {
+				WriteError(w, http.StatusNotFound, "Team not found")
+				return
+			}
+
+			role, err := roles.Role(r.Context(), teamID, userID)
+			if errors.Is(err, storage.ErrNotFound) {
+				WriteError(w, http.StatusNotFound, "Team not found")
+				return
+			}
+			if err != nil {
+				log.Printf("load team role failed for user %s: %v", userID, err)
+				WriteError(w, http.StatusInternalServerError, "Failed to load team")
+				return
+			}
+
+			if !storage.TeamRoleAtLeast(role, minimum) {
+				WriteError(w, http.StatusForbidden, "Team "+minimum+" role required")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), teamRoleKey{}, role)))
+		})
+	}
+}
@@ -0,0 +1,33 @@
+// Package joincode generates the short codes people read out to each other to join races and teams.
+package joincode
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// alphabet leaves out characters that are easy to confuse when read aloud (0/O, 1/I/L).
+const alphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// unbiased is the largest multiple of len(alphabet) that fits in a byte. Bytes at or above it are
+// skipped, so every character of alphabet is equally likely.
+const unbiased = 256 - 256%len(alphabet)
+
+// New returns a random code of length characters from alphabet.
+func New(length int) (string, error) {
+	code := make([]byte, 0, length)
+	buf := make([]byte, length)
+	for len(code) < length {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("generate join code: %w", err)
+		}
+
+		for _, b := range buf {
+			if int(b) < unbiased && len(code) < length {
+				code = append(code, alphabet[int(b)%len(alphabet)])
+			}
+		}
+	}
+
+	return string(code), nil
+}
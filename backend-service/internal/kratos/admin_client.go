@@ -1,13 +1,37 @@
 package kratos
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 )
 
+// ErrIdentityNotFound is returned when Kratos has no identity with the requested ID.
+var ErrIdentityNotFound = errors.New("identity not found")
+
+// Identity states accepted by SetIdentityState. Inactive identities cannot sign in.
+const (
+	IdentityStateActive   = "active"
+	IdentityStateInactive = "inactive"
+)
+
+// Identity is the subset of a Kratos identity this service reads.
+// Traits are kept as raw JSON because their shape is defined by the identity schema.
+type Identity struct {
+	ID             string          `json:"id"`
+	SchemaID       string          `json:"schema_id"`
+	State          string          `json:"state"`
+	Traits         json.RawMessage `json:"traits"`
+	MetadataPublic json.RawMessage `json:"metadata_public,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
 // AdminClient wraps calls to the Kratos Admin API.
 // Only the endpoints needed by this service are implemented.
 type AdminClient struct {
@@ -51,3 +75,78 @@ func (c *AdminClient) DeleteIdentity(ctx context.Context, identityID string) err
 
 	return fmt.Errorf("kratos admin api returned %s", resp.Status)
 }
+
+// GetIdentity fetches the specified identity via the Admin API.
+// Returns ErrIdentityNotFound when Kratos responds with 404.
+func (c *AdminClient) GetIdentity(ctx context.Context, identityID string) (Identity, error) {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		fmt.Sprintf("%s/identities/%s", c.baseURL, identityID),
+		nil,
+	)
+	if err != nil {
+		return Identity{}, fmt.Errorf("build get identity request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return Identity{}, fmt.Errorf("call kratos admin api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return Identity{}, ErrIdentityNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return Identity{}, fmt.Errorf("kratos admin api returned %s", resp.Status)
+	}
+
+	var identity Identity
+	if err := json.NewDecoder(resp.Body).Decode(&identity); err != nil {
+		return Identity{}, fmt.Errorf("decode identity: %w", err)
+	}
+
+	return identity, nil
+}
+
+// SetIdentityState activates or deactivates the specified identity via the Admin API.
+// Returns ErrIdentityNotFound when Kratos responds with 404.
+func (c *AdminClient) SetIdentityState(ctx context.Context, identityID, state string) error {
+	patch, err := json.Marshal([]map[string]string{
+		{"op": "replace", "path": "/state", "value": state},
+	})
+	if err != nil {
+		return fmt.Errorf("encode identity patch: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPatch,
+		fmt.Sprintf("%s/identities/%s", c.baseURL, identityID),
+		bytes.NewReader(patch),
+	)
+	if err != nil {
+		return fmt.Errorf("build patch identity request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("call kratos admin api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrIdentityNotFound
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("kratos admin api returned %s", resp.Status)
+	}
+
+	return nil
+}
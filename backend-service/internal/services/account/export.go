@@ -0,0 +1,328 @@
+package account
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"code-type/backend/internal/kratos"
+	"code-type/backend/internal/storage"
+	"code-type/backend/internal/typing"
+)
+
+// exportFormatVersion is bumped whenever the layout of the archive changes incompatibly.
+const exportFormatVersion = 1
+
+// ExportSources are the repositories holding the user's application data.
+type ExportSources struct {
+	History      *storage.HistoryRepository
+	Profiles     *storage.ProfileRepository
+	Preferences  *storage.PreferencesRepository
+	Goals        *storage.GoalRepository
+	Achievements *storage.AchievementRepository
+	Teams        *storage.TeamRepository
+}
+
+// Exporter builds personal data archives.
+type Exporter struct {
+	adminClient *kratos.AdminClient
+	sources     ExportSources
+}
+
+// NewExporter creates an exporter reading the identity from Kratos and the rest from sources.
+func NewExporter(adminClient *kratos.AdminClient, sources ExportSources) *Exporter {
+	return &Exporter{
+		adminClient: adminClient,
+		sources:     sources,
+	}
+}
+
+// Export is a prepared archive: the identity and the small documents are already loaded,
+// and the history is streamed from the database while the archive is written.
+type Export struct {
+	exporter   *Exporter
+	userID     string
+	exportedAt time.Time
+	documents  []exportDocument
+}
+
+type exportDocument struct {
+	name    string
+	payload any
+}
+
+type manifestRecord struct {
+	FormatVersion int       `json:"format_version"`
+	UserID        string    `json:"user_id"`
+	ExportedAt    time.Time `json:"exported_at"`
+	Files         []string  `json:"files"`
+}
+
+type profileRecord struct {
+	DisplayName      string    `json:"display_name"`
+	LeaderboardOptIn bool      `json:"leaderboard_opt_in"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+type preferencesRecord struct {
+	Theme           string     `json:"theme"`
+	DefaultLanguage string     `json:"default_language"`
+	SnippetLength   string     `json:"snippet_length"`
+	ShowLiveStats   bool       `json:"show_live_stats"`
+	UpdatedAt       *time.Time `json:"updated_at"`
+}
+
+type goalRecord struct {
+	Kind     string `json:"kind"`
+	Language string `json:"language,omitempty"`
+	Target   int    `json:"target"`
+}
+
+type goalsRecord struct {
+	TimeZone string       `json:"time_zone"`
+	Goals    []goalRecord `json:"goals"`
+}
+
+type achievementRecord struct {
+	ID         string    `json:"id"`
+	HistoryID  string    `json:"history_id,omitempty"`
+	UnlockedAt time.Time `json:"unlocked_at"`
+}
+
+type teamRecord struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+type keystrokeRecord struct {
+	C  string `json:"c"`
+	T  int    `json:"t"`
+	OK bool   `json:"ok"`
+}
+
+type historyRecord struct {
+	ID                 string            `json:"id"`
+	Language           string            `json:"language"`
+	SnippetID          string            `json:"snippet_id,omitempty"`
+	SnippetHash        string            `json:"snippet_hash,omitempty"`
+	RaceID             string            `json:"race_id,omitempty"`
+	WPM                int               `json:"wpm"`
+	Accuracy           int               `json:"accuracy"`
+	Errors             int               `json:"errors"`
+	DurationSeconds    int               `json:"duration_seconds"`
+	CompletedAt        time.Time         `json:"completed_at"`
+	CreatedAt          time.Time         `json:"created_at"`
+	VerificationStatus string            `json:"verification_status"`
+	Keystrokes         []keystrokeRecord `json:"keystrokes,omitempty"`
+}
+
+// historyFile is written last because it is the only part streamed from the database.
+const historyFile = "history.ndjson"
+
+// Prepare loads everything except the history. Failures here happen before any output is written,
+// so callers can still report them as a normal error response.
+func (e *Exporter) Prepare(ctx context.Context, userID string) (*Export, error) {
+	identity, err := e.adminClient.GetIdentity(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get identity: %w", err)
+	}
+
+	var profile *profileRecord
+	switch stored, err := e.sources.Profiles.Get(ctx, userID); {
+	case err == nil:
+		profile = &profileRecord{
+			DisplayName:      stored.DisplayName,
+			LeaderboardOptIn: stored.LeaderboardOptIn,
+			CreatedAt:        stored.CreatedAt,
+			UpdatedAt:        stored.UpdatedAt,
+		}
+	case !errors.Is(err, storage.ErrNotFound):
+		return nil, fmt.Errorf("load profile: %w", err)
+	}
+
+	prefs, err := e.sources.Preferences.Get(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("load preferences: %w", err)
+	}
+
+	preferences := preferencesRecord{
+		Theme:           prefs.Theme,
+		DefaultLanguage: prefs.DefaultLanguage,
+		SnippetLength:   prefs.SnippetLength,
+		ShowLiveStats:   prefs.ShowLiveStats,
+	}
+	if !prefs.UpdatedAt.IsZero() {
+		preferences.UpdatedAt = &prefs.UpdatedAt
+	}
+
+	settings, err := e.sources.Goals.Get(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("load goals: %w", err)
+	}
+
+	goals := goalsRecord{TimeZone: settings.TimeZone, Goals: make([]goalRecord, len(settings.Goals))}
+	for i, goal := range settings.Goals {
+		goals.Goals[i] = goalRecord{Kind: goal.Kind, Language: goal.Language, Target: goal.Target}
+	}
+
+	unlocked, err := e.sources.Achievements.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("load achievements: %w", err)
+	}
+
+	achievements := make([]achievementRecord, len(unlocked))
+	for i, achievement := range unlocked {
+		achievements[i] = achievementRecord{
+			ID:         achievement.AchievementID,
+			HistoryID:  achievement.HistoryID,
+			UnlockedAt: achievement.UnlockedAt,
+		}
+	}
+
+	memberships, err := e.sources.Teams.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("load teams: %w", err)
+	}
+
+	teams := make([]teamRecord, len(memberships))
+	for i, membership := range memberships {
+		teams[i] = teamRecord{ID: membership.ID, Name: membership.Name, Role: membership.Role}
+	}
+
+	return &Export{
+		exporter:   e,
+		userID:     userID,
+		exportedAt: time.Now().UTC(),
+		documents: []exportDocument{
+			{name: "identity.json", payload: identity},
+			{name: "profile.json", payload: profile},
+			{name: "preferences.json", payload: preferences},
+			{name: "goals.json", payload: goals},
+			{name: "achievements.json", payload: achievements},
+			{name: "teams.json", payload: teams},
+		},
+	}, nil
+}
+
+// ExportedAt is the time the export was prepared.
+func (x *Export) ExportedAt() time.Time {
+	return x.exportedAt
+}
+
+// WriteZip writes the archive to w: a manifest, one JSON document per data set, and the history
+// as NDJSON with one run per line including its keystroke log.
+func (x *Export) WriteZip(ctx context.Context, w io.Writer) error {
+	archive := zip.NewWriter(w)
+
+	files := []string{"manifest.json"}
+	for _, document := range x.documents {
+		files = append(files, document.name)
+	}
+	files = append(files, historyFile)
+
+	manifest := manifestRecord{
+		FormatVersion: exportFormatVersion,
+		UserID:        x.userID,
+		ExportedAt:    x.exportedAt,
+		Files:         files,
+	}
+
+	if err := x.writeDocument(archive, "manifest.json", manifest); err != nil {
+		return err
+	}
+
+	for _, document := range x.documents {
+		if err := x.writeDocument(archive, document.name, document.payload); err != nil {
+			return err
+		}
+	}
+
+	file, err := x.create(archive, historyFile)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	scan := storage.HistoryScan{
+		UserID:         x.userID,
+		Sort:           storage.HistorySort{Field: "completed_at", Ascending: true},
+		WithKeystrokes: true,
+	}
+
+	err = x.exporter.sources.History.ForEach(ctx, scan,
+		func(entry storage.HistoryEntry, keystrokes []typing.Keystroke) error {
+			if err := encoder.Encode(newHistoryRecord(entry, keystrokes)); err != nil {
+				return fmt.Errorf("write history entry: %w", err)
+			}
+			return nil
+		})
+	if err != nil {
+		return fmt.Errorf("export history: %w", err)
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("finish archive: %w", err)
+	}
+
+	return nil
+}
+
+func (x *Export) writeDocument(archive *zip.Writer, name string, payload any) error {
+	file, err := x.create(archive, name)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(payload); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func (x *Export) create(archive *zip.Writer, name string) (io.Writer, error) {
+	file, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: x.exportedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create %s: %w", name, err)
+	}
+
+	return file, nil
+}
+
+func newHistoryRecord(entry storage.HistoryEntry, keystrokes []typing.Keystroke) historyRecord {
+	record := historyRecord{
+		ID:                 entry.ID,
+		Language:           entry.Language,
+		SnippetID:          entry.SnippetID,
+		SnippetHash:        entry.SnippetHash,
+		RaceID:             entry.RaceID,
+		WPM:                entry.WPM,
+		Accuracy:           entry.Accuracy,
+		Errors:             entry.Errors,
+		DurationSeconds:    entry.DurationSeconds,
+		CompletedAt:        entry.CompletedAt,
+		CreatedAt:          entry.CreatedAt,
+		VerificationStatus: entry.VerificationStatus,
+	}
+
+	if len(keystrokes) > 0 {
+		record.Keystrokes = make([]keystrokeRecord, len(keystrokes))
+		for i, k := range keystrokes {
+			record.Keystrokes[i] = keystrokeRecord{C: k.Char, T: k.OffsetMillis, OK: k.Correct}
+		}
+	}
+
+	return record
+}
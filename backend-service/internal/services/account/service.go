@@ -2,43 +2,214 @@ package account
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
+	"time"
 
 	"code-type/backend/internal/kratos"
+	"code-type/backend/internal/storage"
 )
 
-// HistoryCleaner removes practice history (and other per-user records) from the application DB.
-type HistoryCleaner interface {
-	DeleteByUser(ctx context.Context, userID string) error
-}
+const (
+	// deletionLease is how long an attempt owns a deletion before another replica may retry it.
+	deletionLease = 5 * time.Minute
+	// deletionBatchSize caps the deletions retried per worker tick.
+	deletionBatchSize = 20
+	// restoreTokenBytes is the amount of randomness in a restore token.
+	restoreTokenBytes = 32
+)
+
+// Retries of a failing deletion wait baseDeletionBackoff, doubling per attempt up to maxDeletionBackoff.
+const (
+	baseDeletionBackoff = 30 * time.Second
+	maxDeletionBackoff  = time.Hour
+)
 
 // Service coordinates account deletion across Kratos and application-specific data.
+// A deletion is persisted before anything is removed and then advanced step by step:
+// the Kratos identity is deactivated, so the user can no longer sign in, and the account
+// can be restored until the grace period ends. Then the identity is deleted and all
+// application data is purged in one transaction. Steps that fail are retried by the worker,
+// which also finishes deletions whose grace period has ended.
 type Service struct {
 	adminClient *kratos.AdminClient
-	historyRepo HistoryCleaner
+	deletions   *storage.AccountDeletionRepository
+	grace       time.Duration
 }
 
-// NewService creates a new account service.
-func NewService(adminClient *kratos.AdminClient, historyRepo HistoryCleaner) *Service {
+// DeletionResult describes the state of an account deletion right after it was requested.
+// RestoreToken is set while the deletion can still be restored, which is until PurgeAfter.
+type DeletionResult struct {
+	Completed    bool
+	PurgeAfter   time.Time
+	RestoreToken string
+}
+
+// NewService creates a new account service that keeps deleted accounts restorable for grace.
+func NewService(adminClient *kratos.AdminClient, deletions *storage.AccountDeletionRepository, grace time.Duration) *Service {
 	return &Service{
 		adminClient: adminClient,
-		historyRepo: historyRepo,
+		deletions:   deletions,
+		grace:       grace,
 	}
 }
 
-// DeleteAccount removes the identity in Kratos and purges application data related to that identity.
-func (s *Service) DeleteAccount(ctx context.Context, userID string) error {
+// DeleteAccount records a deletion request for the user and tries to carry it out right away.
+// With a grace period that means deactivating the account and returning a token that restores it;
+// without one the account is deleted immediately. When a step fails the request stays pending
+// and is finished by the background worker, so only failing to record the request is an error.
+func (s *Service) DeleteAccount(ctx context.Context, userID string) (DeletionResult, error) {
 	if userID == "" {
-		return fmt.Errorf("user id is required")
+		return DeletionResult{}, fmt.Errorf("user id is required")
+	}
+
+	token, err := newRestoreToken()
+	if err != nil {
+		return DeletionResult{}, err
+	}
+
+	deletion, err := s.deletions.Request(ctx, userID, hashRestoreToken(token), s.grace, deletionLease)
+	if err != nil {
+		return DeletionResult{}, fmt.Errorf("request account deletion: %w", err)
+	}
+
+	result := DeletionResult{
+		Completed:  s.attempt(ctx, deletion),
+		PurgeAfter: deletion.PurgeAfter,
+	}
+	if !result.Completed && deletion.IdentityDeletedAt == nil && time.Now().Before(deletion.PurgeAfter) {
+		result.RestoreToken = token
+	}
+
+	return result, nil
+}
+
+// RestoreAccount cancels the pending deletion the token was issued for and reactivates the
+// Kratos identity. Returns storage.ErrNotFound when the token is unknown or its grace period is over.
+func (s *Service) RestoreAccount(ctx context.Context, token string) error {
+	if token == "" {
+		return storage.ErrNotFound
 	}
 
-	if err := s.adminClient.DeleteIdentity(ctx, userID); err != nil {
-		return fmt.Errorf("delete identity: %w", err)
+	_, err := s.deletions.Restore(ctx, hashRestoreToken(token), func(ctx context.Context, userID string) error {
+		if err := s.adminClient.SetIdentityState(ctx, userID, kratos.IdentityStateActive); err != nil {
+			return fmt.Errorf("activate identity: %w", err)
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+// ProcessDueDeletions retries pending deletions whose next attempt is due.
+func (s *Service) ProcessDueDeletions(ctx context.Context) error {
+	deletions, err := s.deletions.ClaimDue(ctx, deletionBatchSize, deletionLease)
+	if err != nil {
+		return err
 	}
 
-	if err := s.historyRepo.DeleteByUser(ctx, userID); err != nil {
-		return fmt.Errorf("delete practice history: %w", err)
+	for _, deletion := range deletions {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		s.attempt(ctx, deletion)
 	}
 
 	return nil
 }
+
+// attempt runs the remaining steps of a deletion and reports whether it completed.
+// A failure is recorded with a retry time that backs off with the number of attempts.
+func (s *Service) attempt(ctx context.Context, deletion storage.AccountDeletion) bool {
+	completed, err := s.runSteps(ctx, deletion)
+	if err == nil {
+		return completed
+	}
+
+	// The deletion was restored while this attempt was running; there is nothing left to do.
+	if errors.Is(err, storage.ErrNotFound) {
+		return false
+	}
+
+	log.Printf("account deletion %s failed for user %s (attempt %d): %v", deletion.ID, deletion.UserID, deletion.Attempts, err)
+
+	if err := s.deletions.RecordFailure(ctx, deletion.ID, err, deletionBackoff(deletion.Attempts)); err != nil {
+		log.Printf("record account deletion %s failure: %v", deletion.ID, err)
+	}
+
+	return false
+}
+
+// runSteps reports whether the deletion completed; it stops early, without an error,
+// while the deletion is in its grace period.
+func (s *Service) runSteps(ctx context.Context, deletion storage.AccountDeletion) (bool, error) {
+	if deletion.IdentityDeletedAt == nil {
+		if deletion.DeactivatedAt == nil {
+			if err := s.deletions.Deactivate(ctx, deletion.ID, s.deactivateIdentity); err != nil {
+				return false, err
+			}
+		}
+
+		deferred, err := s.deletions.DeferUntilPurge(ctx, deletion.ID)
+		if err != nil || deferred {
+			return false, err
+		}
+
+		if err := s.adminClient.DeleteIdentity(ctx, deletion.UserID); err != nil {
+			return false, fmt.Errorf("delete identity: %w", err)
+		}
+
+		if err := s.deletions.MarkIdentityDeleted(ctx, deletion.ID); err != nil {
+			return false, err
+		}
+	}
+
+	if err := s.deletions.Purge(ctx, deletion.ID); err != nil {
+		return false, fmt.Errorf("delete application data: %w", err)
+	}
+
+	return true, nil
+}
+
+// deactivateIdentity blocks sign-ins for the user. An identity that is already gone
+// needs no deactivation; it is deleted anyway once the grace period ends.
+func (s *Service) deactivateIdentity(ctx context.Context, userID string) error {
+	err := s.adminClient.SetIdentityState(ctx, userID, kratos.IdentityStateInactive)
+	if err != nil && !errors.Is(err, kratos.ErrIdentityNotFound) {
+		return fmt.Errorf("deactivate identity: %w", err)
+	}
+
+	return nil
+}
+
+func newRestoreToken() (string, error) {
+	buf := make([]byte, restoreTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate restore token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashRestoreToken returns the form a restore token is stored in, so a leaked table cannot restore accounts.
+func hashRestoreToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// deletionBackoff doubles the retry delay with every attempt, up to maxDeletionBackoff.
+func deletionBackoff(attempts int) time.Duration {
+	backoff := baseDeletionBackoff
+	for i := 1; i < attempts && backoff < maxDeletionBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, maxDeletionBackoff)
+}
@@ -0,0 +1,41 @@
+package account
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// DeletionWorker finishes account deletions whose grace period has ended, as well as those
+// whose steps failed when they were requested.
+// It is safe to run on every replica: due deletions are claimed with SKIP LOCKED and a lease.
+type DeletionWorker struct {
+	service  *Service
+	interval time.Duration
+}
+
+// NewDeletionWorker creates a worker that looks for due deletions every interval.
+func NewDeletionWorker(service *Service, interval time.Duration) *DeletionWorker {
+	return &DeletionWorker{
+		service:  service,
+		interval: interval,
+	}
+}
+
+// Run processes due deletions once immediately and then on every tick until ctx is cancelled.
+func (w *DeletionWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.service.ProcessDueDeletions(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("process account deletions failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package achievements
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"code-type/backend/internal/storage"
+)
+
+// queueSize bounds the runs waiting for evaluation.
+const queueSize = 256
+
+// Status is a rule together with the user's progress on it.
+type Status struct {
+	Rule       Rule
+	Unlocked   bool
+	UnlockedAt time.Time
+	HistoryID  string
+}
+
+// Engine evaluates rules whenever a run is saved and records newly unlocked achievements.
+type Engine struct {
+	rules  []Rule
+	repo   *storage.AchievementRepository
+	goals  *storage.GoalRepository
+	events chan storage.HistoryEntry
+}
+
+// NewEngine creates an engine for the given rules. Streaks are computed in the user's goal time zone.
+func NewEngine(rules []Rule, repo *storage.AchievementRepository, goals *storage.GoalRepository) *Engine {
+	return &Engine{
+		rules:  rules,
+		repo:   repo,
+		goals:  goals,
+		events: make(chan storage.HistoryEntry, queueSize),
+	}
+}
+
+// Notify queues a saved run for evaluation without blocking; it is meant to be registered
+// with HistoryRepository.OnCreate. When the queue is full the run is skipped: rules are based on
+// lifetime totals, so anything it would have unlocked is picked up by the user's next run.
+func (e *Engine) Notify(entry storage.HistoryEntry) {
+	select {
+	case e.events <- entry:
+	default:
+		log.Printf("achievement queue is full, skipping run %s", entry.ID)
+	}
+}
+
+// Run evaluates queued runs until ctx is cancelled.
+func (e *Engine) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry := <-e.events:
+			if _, err := e.Evaluate(ctx, entry.UserID, entry.ID); err != nil && ctx.Err() == nil {
+				log.Printf("evaluate achievements failed for user %s: %v", entry.UserID, err)
+			}
+		}
+	}
+}
+
+// Evaluate checks every rule the user has not unlocked yet and returns the ones unlocked now,
+// attributing them to historyID.
+func (e *Engine) Evaluate(ctx context.Context, userID, historyID string) ([]Rule, error) {
+	unlocked, err := e.unlockedByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make(map[string]storage.AchievementMetrics)
+	longestStreak := -1
+
+	newlyUnlocked := make([]Rule, 0)
+	for _, rule := range e.rules {
+		if _, ok := unlocked[rule.ID]; ok {
+			continue
+		}
+
+		var value int
+		if rule.Metric == MetricLongestStreak {
+			if longestStreak < 0 {
+				if longestStreak, err = e.longestStreak(ctx, userID); err != nil {
+					return nil, err
+				}
+			}
+			value = longestStreak
+		} else {
+			totals, ok := metrics[rule.Language]
+			if !ok {
+				if totals, err = e.repo.Metrics(ctx, userID, rule.Language); err != nil {
+					return nil, err
+				}
+				metrics[rule.Language] = totals
+			}
+			value = metricValue(totals, rule.Metric)
+		}
+
+		if value < rule.Threshold {
+			continue
+		}
+
+		inserted, err := e.repo.Unlock(ctx, userID, rule.ID, historyID)
+		if err != nil {
+			return nil, err
+		}
+
+		if inserted {
+			newlyUnlocked = append(newlyUnlocked, rule)
+		}
+	}
+
+	return newlyUnlocked, nil
+}
+
+// List returns every configured rule with the user's unlock state, in config order.
+func (e *Engine) List(ctx context.Context, userID string) ([]Status, error) {
+	unlocked, err := e.unlockedByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(e.rules))
+	for i, rule := range e.rules {
+		statuses[i] = Status{Rule: rule}
+		if achievement, ok := unlocked[rule.ID]; ok {
+			statuses[i].Unlocked = true
+			statuses[i].UnlockedAt = achievement.UnlockedAt
+			statuses[i].HistoryID = achievement.HistoryID
+		}
+	}
+
+	return statuses, nil
+}
+
+func (e *Engine) unlockedByID(ctx context.Context, userID string) (map[string]storage.UnlockedAchievement, error) {
+	achievements, err := e.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]storage.UnlockedAchievement, len(achievements))
+	for _, achievement := range achievements {
+		byID[achievement.AchievementID] = achievement
+	}
+
+	return byID, nil
+}
+
+func (e *Engine) longestStreak(ctx context.Context, userID string) (int, error) {
+	settings, err := e.goals.Get(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("load time zone: %w", err)
+	}
+
+	streaks, err := e.goals.Streaks(ctx, userID, settings.TimeZone)
+	if err != nil {
+		return 0, err
+	}
+
+	longest := 0
+	for _, streak := range streaks {
+		longest = max(longest, streak.Days)
+	}
+
+	return longest, nil
+}
+
+func metricValue(metrics storage.AchievementMetrics, metric string) int {
+	switch metric {
+	case MetricRuns:
+		return metrics.Runs
+	case MetricTotalSeconds:
+		return metrics.TotalSeconds
+	case MetricTotalErrors:
+		return metrics.TotalErrors
+	case MetricBestWPM:
+		return metrics.BestWPM
+	case MetricBestAccuracy:
+		return metrics.BestAccuracy
+	default:
+		return 0
+	}
+}
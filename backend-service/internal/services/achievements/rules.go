@@ -0,0 +1,84 @@
+// Package achievements unlocks badges when practice history reaches thresholds defined in a rules file.
+package achievements
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// Metrics a rule can be evaluated against. All of them are lifetime values.
+const (
+	MetricRuns          = "runs"
+	MetricTotalSeconds  = "total_seconds"
+	MetricTotalErrors   = "total_errors"
+	MetricBestWPM       = "best_wpm"
+	MetricBestAccuracy  = "best_accuracy"
+	MetricLongestStreak = "longest_streak"
+)
+
+//go:embed rules.json
+var defaultRules []byte
+
+var ruleIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,63}$`)
+
+// Rule unlocks an achievement once Metric reaches Threshold.
+// Language restricts run-based metrics to runs in that language; streaks count every language.
+type Rule struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Metric      string `json:"metric"`
+	Language    string `json:"language,omitempty"`
+	Threshold   int    `json:"threshold"`
+}
+
+// LoadRules reads rules from a JSON file, or the built-in set when path is empty.
+func LoadRules(path string) ([]Rule, error) {
+	data := defaultRules
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("read achievement rules: %w", err)
+		}
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("decode achievement rules: %w", err)
+	}
+
+	seen := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if !ruleIDPattern.MatchString(rule.ID) {
+			return nil, fmt.Errorf("achievement rule id %q is invalid", rule.ID)
+		}
+
+		if seen[rule.ID] {
+			return nil, fmt.Errorf("achievement rule %q is defined twice", rule.ID)
+		}
+		seen[rule.ID] = true
+
+		if rule.Title == "" {
+			return nil, fmt.Errorf("achievement rule %q has no title", rule.ID)
+		}
+
+		switch rule.Metric {
+		case MetricRuns, MetricTotalSeconds, MetricTotalErrors, MetricBestWPM, MetricBestAccuracy:
+		case MetricLongestStreak:
+			if rule.Language != "" {
+				return nil, fmt.Errorf("achievement rule %q: streaks cannot be limited to a language", rule.ID)
+			}
+		default:
+			return nil, fmt.Errorf("achievement rule %q has unknown metric %q", rule.ID, rule.Metric)
+		}
+
+		if rule.Threshold <= 0 {
+			return nil, fmt.Errorf("achievement rule %q needs a positive threshold", rule.ID)
+		}
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,47 @@
+// Package challenges opens and closes team challenges on schedule.
+package challenges
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Schedulable is implemented by storage that can advance challenges whose window started or ended.
+type Schedulable interface {
+	RunSchedule(ctx context.Context) error
+}
+
+// Scheduler periodically opens challenges that have started and closes those that have ended.
+// It is safe to run on every replica: storage serializes the work and finalizes each challenge once.
+type Scheduler struct {
+	repo     Schedulable
+	interval time.Duration
+}
+
+// NewScheduler creates a scheduler that runs every interval.
+func NewScheduler(repo Schedulable, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		repo:     repo,
+		interval: interval,
+	}
+}
+
+// Run advances the schedule once immediately and then on every tick until ctx is cancelled.
+// Failures are logged and retried on the next tick.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.repo.RunSchedule(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("run challenge schedule failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
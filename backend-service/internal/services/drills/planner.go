@@ -0,0 +1,203 @@
+// Package drills builds practice sessions that focus on the characters a user mistypes most.
+package drills
+
+import (
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"unicode"
+
+	"code-type/backend/internal/storage"
+)
+
+const (
+	// minKeyPresses is the sample size below which a key's error rate is considered noise.
+	minKeyPresses = 5
+	// maxWeaknesses bounds how many tokens a drill focuses on.
+	maxWeaknesses = 8
+	// lineLength is the approximate length of a synthesized drill line.
+	lineLength = 48
+)
+
+// Weakness is a key or bigram the user struggles with, weighted by how much it costs them.
+type Weakness struct {
+	Token  string
+	Weight float64
+}
+
+// ScoredSnippet is a catalog snippet with its relevance to the user's weaknesses.
+type ScoredSnippet struct {
+	Snippet storage.Snippet
+	Score   float64
+}
+
+// Options controls the size of a drill. Seed makes the selection reproducible.
+type Options struct {
+	Seed     uint64
+	Snippets int
+	Lines    int
+}
+
+// Drill is the generated practice plan.
+type Drill struct {
+	Seed       uint64
+	Weaknesses []Weakness
+	Snippets   []ScoredSnippet
+	Lines      []string
+}
+
+// Weaknesses ranks keys by smoothed error rate and latency, and bigrams by latency,
+// relative to the user's own averages. The result is sorted by weight (then token) and capped.
+func Weaknesses(keys []storage.KeyAnalytics, bigrams []storage.BigramAnalytics) []Weakness {
+	var latencySum, latencyCount float64
+	for _, key := range keys {
+		if key.LatencySampleSize > 0 {
+			latencySum += key.AverageLatencyMs
+			latencyCount++
+		}
+	}
+
+	meanLatency := 0.0
+	if latencyCount > 0 {
+		meanLatency = latencySum / latencyCount
+	}
+
+	weaknesses := make([]Weakness, 0, len(keys)+len(bigrams))
+	for _, key := range keys {
+		if key.Presses < minKeyPresses {
+			continue
+		}
+
+		// Laplace smoothing keeps rarely typed keys from dominating with a single mistake.
+		weight := float64(key.Errors+1) / float64(key.Presses+2)
+		if meanLatency > 0 && key.AverageLatencyMs > meanLatency {
+			weight += 0.5 * (key.AverageLatencyMs/meanLatency - 1)
+		}
+
+		weaknesses = append(weaknesses, Weakness{Token: key.Key, Weight: weight})
+	}
+
+	for _, bigram := range bigrams {
+		if meanLatency <= 0 || bigram.AverageLatencyMs <= meanLatency {
+			continue
+		}
+
+		weaknesses = append(weaknesses, Weakness{Token: bigram.Bigram, Weight: 0.5 * (bigram.AverageLatencyMs/meanLatency - 1)})
+	}
+
+	sort.Slice(weaknesses, func(i, j int) bool {
+		if weaknesses[i].Weight != weaknesses[j].Weight {
+			return weaknesses[i].Weight > weaknesses[j].Weight
+		}
+		return weaknesses[i].Token < weaknesses[j].Token
+	})
+
+	if len(weaknesses) > maxWeaknesses {
+		weaknesses = weaknesses[:maxWeaknesses]
+	}
+
+	return weaknesses
+}
+
+// Plan picks snippets weighted toward the given weaknesses and synthesizes short drill lines
+// from them. The result depends only on its inputs, so the same seed reproduces the same drill.
+func Plan(weaknesses []Weakness, snippets []storage.Snippet, opts Options) Drill {
+	rng := rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x9e3779b97f4a7c15))
+
+	drill := Drill{
+		Seed:       opts.Seed,
+		Weaknesses: weaknesses,
+		Snippets:   make([]ScoredSnippet, 0, opts.Snippets),
+		Lines:      make([]string, 0, opts.Lines),
+	}
+
+	candidates := make([]ScoredSnippet, len(snippets))
+	for i, snippet := range snippets {
+		candidates[i] = ScoredSnippet{Snippet: snippet, Score: scoreSnippet(snippet.Content, weaknesses)}
+	}
+
+	// Weighted sampling without replacement.
+	for len(drill.Snippets) < opts.Snippets && len(candidates) > 0 {
+		total := 0.0
+		for _, candidate := range candidates {
+			total += candidate.Score
+		}
+
+		target := rng.Float64() * total
+		index := len(candidates) - 1
+		for i, candidate := range candidates {
+			target -= candidate.Score
+			if target < 0 {
+				index = i
+				break
+			}
+		}
+
+		drill.Snippets = append(drill.Snippets, candidates[index])
+		candidates = append(candidates[:index], candidates[index+1:]...)
+	}
+
+	tokens := drillTokens(weaknesses)
+	if len(tokens) == 0 {
+		return drill
+	}
+
+	for len(drill.Lines) < opts.Lines {
+		var line strings.Builder
+		for line.Len() < lineLength {
+			if line.Len() > 0 {
+				line.WriteByte(' ')
+			}
+			line.WriteString(pickToken(rng, tokens))
+		}
+		drill.Lines = append(drill.Lines, line.String())
+	}
+
+	return drill
+}
+
+// scoreSnippet measures how densely a snippet exercises the weaknesses.
+// A small floor keeps every snippet selectable so drills still vary.
+func scoreSnippet(content string, weaknesses []Weakness) float64 {
+	length := len([]rune(content))
+	if length == 0 {
+		return 0
+	}
+
+	score := 0.0
+	for _, weakness := range weaknesses {
+		score += weakness.Weight * float64(strings.Count(content, weakness.Token))
+	}
+
+	return 0.01 + 100*score/float64(length)
+}
+
+// drillTokens keeps the weaknesses that can be practiced on a single line;
+// whitespace-only tokens such as indentation are left to full snippets.
+func drillTokens(weaknesses []Weakness) []Weakness {
+	tokens := make([]Weakness, 0, len(weaknesses))
+	for _, weakness := range weaknesses {
+		if strings.TrimFunc(weakness.Token, unicode.IsSpace) == weakness.Token && weakness.Token != "" {
+			tokens = append(tokens, weakness)
+		}
+	}
+
+	return tokens
+}
+
+func pickToken(rng *rand.Rand, tokens []Weakness) string {
+	total := 0.0
+	for _, token := range tokens {
+		total += token.Weight
+	}
+
+	target := rng.Float64() * total
+	for _, token := range tokens {
+		target -= token.Weight
+		if target < 0 {
+			return token.Token
+		}
+	}
+
+	return tokens[len(tokens)-1].Token
+}
@@ -0,0 +1,110 @@
+package drills
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"code-type/backend/internal/storage"
+)
+
+func planFixture() ([]Weakness, []storage.Snippet) {
+	weaknesses := []Weakness{
+		{Token: "{", Weight: 0.9},
+		{Token: "err", Weight: 0.6},
+		{Token: ":=", Weight: 0.4},
+		{Token: ";", Weight: 0.2},
+	}
+
+	snippets := []storage.Snippet{
+		{ID: "s1", Content: "if err != nil {\n\treturn err\n}"},
+		{ID: "s2", Content: "x := map[string]int{}"},
+		{ID: "s3", Content: "for i := 0; i < n; i++ {}"},
+		{ID: "s4", Content: "const answer = 42;"},
+		{ID: "s5", Content: "print('hello world')"},
+		{ID: "s6", Content: "func f() error { return nil }"},
+		{ID: "s7", Content: "let total = items.length;"},
+		{ID: "s8", Content: "value, err := parse(input)"},
+	}
+
+	return weaknesses, snippets
+}
+
+func snippetIDs(drill Drill) []string {
+	ids := make([]string, len(drill.Snippets))
+	for i, snippet := range drill.Snippets {
+		ids[i] = snippet.Snippet.ID
+	}
+	return ids
+}
+
+func TestPlanIsReproducibleForASeed(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{name: "zero seed", opts: Options{Seed: 0, Snippets: 3, Lines: 4}},
+		{name: "small seed", opts: Options{Seed: 42, Snippets: 3, Lines: 4}},
+		{name: "large seed", opts: Options{Seed: 1 << 60, Snippets: 5, Lines: 2}},
+		{name: "more snippets than the catalog", opts: Options{Seed: 7, Snippets: 20, Lines: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weaknesses, snippets := planFixture()
+
+			first := Plan(weaknesses, snippets, tt.opts)
+			second := Plan(weaknesses, snippets, tt.opts)
+
+			if !reflect.DeepEqual(first, second) {
+				t.Fatalf("same seed produced different drills:\n%+v\n%+v", first, second)
+			}
+
+			if first.Seed != tt.opts.Seed {
+				t.Errorf("drill seed = %d, want %d", first.Seed, tt.opts.Seed)
+			}
+
+			if want := min(tt.opts.Snippets, len(snippets)); len(first.Snippets) != want {
+				t.Errorf("got %d snippets, want %d", len(first.Snippets), want)
+			}
+
+			if len(first.Lines) != tt.opts.Lines {
+				t.Errorf("got %d lines, want %d", len(first.Lines), tt.opts.Lines)
+			}
+		})
+	}
+}
+
+func TestPlanVariesWithTheSeed(t *testing.T) {
+	tests := []struct {
+		name  string
+		seeds []uint64
+	}{
+		{name: "consecutive seeds", seeds: []uint64{1, 2, 3, 4}},
+		{name: "spread out seeds", seeds: []uint64{10, 1000, 100000, 1 << 40}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			weaknesses, snippets := planFixture()
+			opts := Options{Snippets: 3, Lines: 2}
+
+			picks := make(map[string]bool)
+			lines := make(map[string]bool)
+			for _, seed := range tt.seeds {
+				opts.Seed = seed
+				drill := Plan(weaknesses, snippets, opts)
+				picks[strings.Join(snippetIDs(drill), ",")] = true
+				lines[strings.Join(drill.Lines, "\n")] = true
+			}
+
+			if len(picks) < 2 {
+				t.Errorf("seeds %v all picked the same snippets", tt.seeds)
+			}
+
+			if len(lines) < 2 {
+				t.Errorf("seeds %v all synthesized the same lines", tt.seeds)
+			}
+		})
+	}
+}
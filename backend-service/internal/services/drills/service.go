@@ -0,0 +1,51 @@
+package drills
+
+import (
+	"context"
+	"fmt"
+
+	"code-type/backend/internal/storage"
+)
+
+const (
+	// catalogPoolSize bounds how many catalog snippets are scored per request.
+	catalogPoolSize = 500
+	// bigramPoolSize and bigramMinOccurrences select the bigrams considered as weaknesses.
+	bigramPoolSize       = 20
+	bigramMinOccurrences = 3
+)
+
+// Service loads a user's keystroke analytics and the snippet catalog and plans drills from them.
+type Service struct {
+	analytics *storage.AnalyticsRepository
+	snippets  *storage.SnippetRepository
+}
+
+// NewService creates a new drills service.
+func NewService(analytics *storage.AnalyticsRepository, snippets *storage.SnippetRepository) *Service {
+	return &Service{
+		analytics: analytics,
+		snippets:  snippets,
+	}
+}
+
+// Next plans the next drill for the user in the given language.
+// Users without keystroke analytics get an unweighted selection and no synthesized lines.
+func (s *Service) Next(ctx context.Context, userID, language string, opts Options) (Drill, error) {
+	keys, err := s.analytics.KeyStats(ctx, userID, language)
+	if err != nil {
+		return Drill{}, fmt.Errorf("load key stats: %w", err)
+	}
+
+	bigrams, err := s.analytics.SlowestBigrams(ctx, userID, language, bigramMinOccurrences, bigramPoolSize)
+	if err != nil {
+		return Drill{}, fmt.Errorf("load bigram stats: %w", err)
+	}
+
+	snippets, err := s.snippets.List(ctx, storage.SnippetFilter{Language: language, Limit: catalogPoolSize})
+	if err != nil {
+		return Drill{}, fmt.Errorf("load snippets: %w", err)
+	}
+
+	return Plan(Weaknesses(keys, bigrams), snippets, opts), nil
+}
@@ -0,0 +1,46 @@
+// Package leaderboards keeps the precomputed leaderboards up to date.
+package leaderboards
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Refreshable is implemented by storage that can recompute the boards.
+type Refreshable interface {
+	Refresh(ctx context.Context) error
+}
+
+// Refresher periodically recomputes the leaderboards so reads never scan practice history.
+type Refresher struct {
+	repo     Refreshable
+	interval time.Duration
+}
+
+// NewRefresher creates a refresher that runs every interval.
+func NewRefresher(repo Refreshable, interval time.Duration) *Refresher {
+	return &Refresher{
+		repo:     repo,
+		interval: interval,
+	}
+}
+
+// Run refreshes once immediately and then on every tick until ctx is cancelled.
+// Failures are logged and retried on the next tick.
+func (r *Refresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.repo.Refresh(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("refresh leaderboards failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
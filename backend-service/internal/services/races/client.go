@@ -0,0 +1,100 @@
+package races
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = pongWait * 9 / 10
+	maxMessageSize = 4 << 20 // finish messages carry the keystroke log
+	sendBufferSize = 64
+)
+
+// client is one participant's connection. Only the room goroutine writes to or closes send.
+type client struct {
+	participant Participant
+	conn        *websocket.Conn
+	send        chan []byte
+}
+
+func newClient(participant Participant, conn *websocket.Conn) *client {
+	return &client{
+		participant: participant,
+		conn:        conn,
+		send:        make(chan []byte, sendBufferSize),
+	}
+}
+
+type inbound struct {
+	client  *client
+	message clientMessage
+}
+
+// readPump forwards client messages to the room until the connection fails.
+func (c *client) readPump(r *room) {
+	defer func() {
+		select {
+		case r.unregister <- c:
+		case <-r.done:
+		}
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var message clientMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			message = clientMessage{Type: "invalid"}
+		}
+
+		select {
+		case r.inbound <- inbound{client: c, message: message}:
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// writePump delivers queued messages and keeps the connection alive.
+// It closes the connection once the room closes send.
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				_ = c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
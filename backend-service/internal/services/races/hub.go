@@ -0,0 +1,169 @@
+// Package races runs multiplayer typing races. Rooms live in memory in a Hub; every room is owned by
+// a single goroutine, so room state is never shared between connections.
+package races
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"code-type/backend/internal/typing"
+)
+
+// ErrRoomClosed is returned when connecting to a race whose room is not open in this process.
+var ErrRoomClosed = errors.New("race room is closed")
+
+// RejectedError is returned by Store.SaveResult when server-side verification rejects a result.
+type RejectedError struct {
+	Reasons []string
+}
+
+func (e RejectedError) Error() string {
+	return "result rejected"
+}
+
+// Room is the in-memory description of a race needed to run it.
+type Room struct {
+	ID          string
+	OwnerID     string
+	Language    string
+	SnippetID   string
+	SnippetHash string
+	Snippet     string
+}
+
+// Participant identifies a connection. ID is the public identifier shared with other players;
+// the Kratos identity in UserID never leaves the server.
+type Participant struct {
+	ID     string
+	UserID string
+	Name   string
+}
+
+// Result is a finished run to be recorded in practice history.
+type Result struct {
+	Room            Room
+	UserID          string
+	WPM             int
+	Accuracy        int
+	Errors          int
+	DurationSeconds int
+	CompletedAt     time.Time
+	Keystrokes      []typing.Keystroke
+}
+
+// SavedResult is the stored version of a Result, after server-side verification.
+type SavedResult struct {
+	HistoryID          string
+	WPM                int
+	Accuracy           int
+	Errors             int
+	DurationSeconds    int
+	VerificationStatus string
+}
+
+// Store persists what happens in a room.
+type Store interface {
+	SetStatus(ctx context.Context, raceID, status string) error
+	SaveResult(ctx context.Context, result Result) (SavedResult, error)
+}
+
+// Options tunes room timing.
+type Options struct {
+	// Countdown is the delay between the owner starting the race and typing starting.
+	Countdown time.Duration
+	// MaxDuration ends a running race even if some participants never finish.
+	MaxDuration time.Duration
+	// IdleTimeout expires rooms that are never started.
+	IdleTimeout time.Duration
+	// MaxParticipants bounds the room size.
+	MaxParticipants int
+	// Lease is how long an open race survives without a heartbeat from the instance running its room.
+	Lease time.Duration
+}
+
+// DefaultOptions are used by the API.
+var DefaultOptions = Options{
+	Countdown:       5 * time.Second,
+	MaxDuration:     10 * time.Minute,
+	IdleTimeout:     30 * time.Minute,
+	MaxParticipants: 8,
+	Lease:           time.Minute,
+}
+
+// Hub tracks open rooms. It is safe for concurrent use.
+type Hub struct {
+	ctx   context.Context
+	store Store
+	opts  Options
+
+	mu    sync.Mutex
+	rooms map[string]*room
+}
+
+// NewHub creates a hub whose rooms stop when ctx is cancelled.
+func NewHub(ctx context.Context, store Store, opts Options) *Hub {
+	return &Hub{
+		ctx:   ctx,
+		store: store,
+		opts:  opts,
+		rooms: make(map[string]*room),
+	}
+}
+
+// Open starts a room for the race. Opening a room twice is a no-op.
+func (h *Hub) Open(spec Room) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.rooms[spec.ID]; ok {
+		return
+	}
+
+	r := newRoom(h, spec)
+	h.rooms[spec.ID] = r
+	go r.run()
+}
+
+// IsOpen reports whether the race has a live room.
+func (h *Hub) IsOpen(raceID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, ok := h.rooms[raceID]
+	return ok
+}
+
+// Serve attaches an upgraded connection to the race room and blocks until it disconnects.
+// A newer connection of the same participant replaces the older one.
+func (h *Hub) Serve(raceID string, participant Participant, conn *websocket.Conn) error {
+	h.mu.Lock()
+	r, ok := h.rooms[raceID]
+	h.mu.Unlock()
+
+	if !ok {
+		return ErrRoomClosed
+	}
+
+	c := newClient(participant, conn)
+	select {
+	case r.register <- c:
+	case <-r.done:
+		return ErrRoomClosed
+	}
+
+	go c.writePump()
+	c.readPump(r)
+
+	return nil
+}
+
+func (h *Hub) remove(raceID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.rooms, raceID)
+}
@@ -0,0 +1,107 @@
+package races
+
+// Message types sent by clients.
+const (
+	clientStart    = "start"
+	clientProgress = "progress"
+	clientFinish   = "finish"
+)
+
+// clientMessage is any message a participant sends over the socket.
+// Start is only honoured from the race owner; Position and Errors describe progress;
+// Keystrokes is the optional log sent with finish, with offsets measured from the race start.
+type clientMessage struct {
+	Type       string             `json:"type"`
+	Position   int                `json:"position"`
+	Errors     int                `json:"errors"`
+	Keystrokes []keystrokePayload `json:"keystrokes,omitempty"`
+}
+
+type keystrokePayload struct {
+	C  string `json:"c"`
+	T  int    `json:"t"`
+	OK bool   `json:"ok"`
+}
+
+type snippetPayload struct {
+	ID       string `json:"id,omitempty"`
+	Language string `json:"language"`
+	Content  string `json:"content"`
+}
+
+type playerPayload struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Owner     bool   `json:"owner"`
+	Connected bool   `json:"connected"`
+	Racing    bool   `json:"racing"`
+	Position  int    `json:"position"`
+	Errors    int    `json:"errors"`
+	Finished  bool   `json:"finished"`
+}
+
+type resultPayload struct {
+	Participant        string `json:"participant"`
+	Name               string `json:"name"`
+	Place              int    `json:"place"`
+	WPM                int    `json:"wpm"`
+	Accuracy           int    `json:"accuracy"`
+	Errors             int    `json:"errors"`
+	DurationSeconds    int    `json:"duration_seconds"`
+	HistoryID          string `json:"history_id"`
+	VerificationStatus string `json:"verification_status"`
+}
+
+// stateMessage is sent to a participant when they connect. StartsAt and StartedAt are RFC3339
+// timestamps with millisecond precision so every client counts down to the same instant.
+type stateMessage struct {
+	Type      string          `json:"type"`
+	RaceID    string          `json:"race_id"`
+	You       string          `json:"you"`
+	Status    string          `json:"status"`
+	Snippet   snippetPayload  `json:"snippet"`
+	Players   []playerPayload `json:"players"`
+	Results   []resultPayload `json:"results"`
+	StartsAt  string          `json:"starts_at,omitempty"`
+	StartedAt string          `json:"started_at,omitempty"`
+}
+
+type playerMessage struct {
+	Type   string        `json:"type"`
+	Player playerPayload `json:"player"`
+}
+
+type countdownMessage struct {
+	Type     string `json:"type"`
+	StartsAt string `json:"starts_at"`
+	Seconds  int    `json:"seconds"`
+}
+
+type startMessage struct {
+	Type      string `json:"type"`
+	StartedAt string `json:"started_at"`
+}
+
+type progressMessage struct {
+	Type        string `json:"type"`
+	Participant string `json:"participant"`
+	Position    int    `json:"position"`
+	Errors      int    `json:"errors"`
+	WPM         int    `json:"wpm"`
+}
+
+type finishedMessage struct {
+	Type   string        `json:"type"`
+	Result resultPayload `json:"result"`
+}
+
+type resultsMessage struct {
+	Type    string          `json:"type"`
+	Status  string          `json:"status"`
+	Results []resultPayload `json:"results"`
+}
+
+type errorMessage struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
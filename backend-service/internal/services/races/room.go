@@ -0,0 +1,482 @@
+package races
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"code-type/backend/internal/storage"
+	"code-type/backend/internal/typing"
+)
+
+// storeTimeout bounds database calls made from a room goroutine.
+const storeTimeout = 5 * time.Second
+
+// player is the room's view of a participant.
+// Racing is set for participants connected when the countdown began; others only watch.
+type player struct {
+	participant Participant
+	connected   bool
+	racing      bool
+	position    int
+	errors      int
+	finished    bool
+	result      *resultPayload
+}
+
+// room runs one race. All fields are owned by the run goroutine; other goroutines
+// talk to it through the register, unregister and inbound channels.
+type room struct {
+	hub           *Hub
+	spec          Room
+	snippetLength int
+
+	register   chan *client
+	unregister chan *client
+	inbound    chan inbound
+	done       chan struct{}
+
+	clients   map[string]*client
+	players   map[string]*player
+	order     []string
+	status    string
+	startsAt  time.Time
+	startedAt time.Time
+	places    int
+
+	idle      *time.Timer
+	countdown *time.Timer
+	deadline  *time.Timer
+}
+
+func newRoom(h *Hub, spec Room) *room {
+	return &room{
+		hub:           h,
+		spec:          spec,
+		snippetLength: len([]rune(spec.Snippet)),
+		register:      make(chan *client),
+		unregister:    make(chan *client),
+		inbound:       make(chan inbound),
+		done:          make(chan struct{}),
+		clients:       make(map[string]*client),
+		players:       make(map[string]*player),
+		status:        storage.RaceWaiting,
+	}
+}
+
+func (r *room) run() {
+	defer r.close()
+
+	r.idle = time.NewTimer(r.hub.opts.IdleTimeout)
+	defer r.idle.Stop()
+
+	for {
+		select {
+		case <-r.hub.ctx.Done():
+			return
+		case c := <-r.register:
+			r.handleRegister(c)
+		case c := <-r.unregister:
+			r.handleUnregister(c)
+		case in := <-r.inbound:
+			r.handleMessage(in.client, in.message)
+		case <-r.idle.C:
+			if r.status == storage.RaceWaiting {
+				r.end(storage.RaceExpired)
+			}
+		case <-timerC(r.countdown):
+			r.begin()
+		case <-timerC(r.deadline):
+			r.end(storage.RaceFinished)
+		}
+
+		if r.status == storage.RaceFinished || r.status == storage.RaceExpired {
+			return
+		}
+	}
+}
+
+func (r *room) handleRegister(c *client) {
+	id := c.participant.ID
+	if previous, ok := r.clients[id]; ok {
+		delete(r.clients, id)
+		close(previous.send)
+	}
+
+	p, ok := r.players[id]
+	if !ok {
+		p = &player{participant: c.participant}
+		r.players[id] = p
+		r.order = append(r.order, id)
+	}
+	p.connected = true
+
+	r.broadcast(playerMessage{Type: "joined", Player: r.playerPayload(p)})
+	r.clients[id] = c
+	r.sendTo(c, r.state(id))
+}
+
+func (r *room) handleUnregister(c *client) {
+	id := c.participant.ID
+	if r.clients[id] != c {
+		// Already replaced by a newer connection.
+		return
+	}
+
+	delete(r.clients, id)
+	close(c.send)
+
+	p := r.players[id]
+	p.connected = false
+	r.broadcast(playerMessage{Type: "left", Player: r.playerPayload(p)})
+
+	if r.status == storage.RaceRunning && r.allDone() {
+		r.end(storage.RaceFinished)
+	}
+}
+
+func (r *room) handleMessage(c *client, message clientMessage) {
+	if r.clients[c.participant.ID] != c {
+		return
+	}
+
+	switch message.Type {
+	case clientStart:
+		r.handleStart(c)
+	case clientProgress:
+		r.handleProgress(c, message)
+	case clientFinish:
+		r.handleFinish(c, message)
+	default:
+		r.sendError(c, "unknown message type")
+	}
+}
+
+// handleStart lets the owner begin the countdown. Everyone connected at this point races.
+func (r *room) handleStart(c *client) {
+	if c.participant.UserID != r.spec.OwnerID {
+		r.sendError(c, "only the race owner can start the race")
+		return
+	}
+
+	if r.status != storage.RaceWaiting {
+		r.sendError(c, "race already started")
+		return
+	}
+
+	r.status = storage.RaceCountdown
+	r.idle.Stop()
+	for id := range r.clients {
+		r.players[id].racing = true
+	}
+
+	r.setStatus(storage.RaceCountdown)
+
+	r.startsAt = time.Now().Add(r.hub.opts.Countdown)
+	r.countdown = time.NewTimer(r.hub.opts.Countdown)
+	r.broadcast(countdownMessage{
+		Type:     "countdown",
+		StartsAt: formatTime(r.startsAt),
+		Seconds:  int(r.hub.opts.Countdown.Round(time.Second) / time.Second),
+	})
+}
+
+func (r *room) begin() {
+	r.countdown = nil
+	r.status = storage.RaceRunning
+	r.startedAt = time.Now()
+	r.deadline = time.NewTimer(r.hub.opts.MaxDuration)
+
+	r.setStatus(storage.RaceRunning)
+	r.broadcast(startMessage{Type: "start", StartedAt: formatTime(r.startedAt)})
+
+	if r.allDone() {
+		r.end(storage.RaceFinished)
+	}
+}
+
+func (r *room) handleProgress(c *client, message clientMessage) {
+	p := r.players[c.participant.ID]
+	if r.status != storage.RaceRunning || !p.racing || p.finished {
+		return
+	}
+
+	// Reported positions only move forward, and never beyond what a typist at the plausible
+	// maximum speed could have reached, so a client cannot jump to the end of the snippet.
+	p.position = min(max(message.Position, p.position), r.reachable(time.Now()))
+	p.errors = max(message.Errors, p.errors)
+
+	r.broadcast(progressMessage{
+		Type:        "progress",
+		Participant: p.participant.ID,
+		Position:    p.position,
+		Errors:      p.errors,
+		WPM:         typing.CalculateWPM(p.position, int(time.Since(r.startedAt)/time.Second)),
+	})
+}
+
+// handleFinish records the participant's run. Speed is computed from the server's clock, which
+// started the race; the optional keystroke log lets the store verify and refine the numbers.
+// A finish is only accepted once the player's progress reached the end of the snippet, or with
+// a keystroke log that types the whole snippet within the time the race has been running.
+func (r *room) handleFinish(c *client, message clientMessage) {
+	p := r.players[c.participant.ID]
+	if r.status != storage.RaceRunning || !p.racing || p.finished {
+		r.sendError(c, "not racing")
+		return
+	}
+
+	if message.Errors < 0 {
+		r.sendError(c, "errors must be non-negative")
+		return
+	}
+
+	now := time.Now()
+	seconds := max(1, int(now.Sub(r.startedAt)/time.Second))
+
+	if len(message.Keystrokes) > typing.MaxKeystrokes {
+		r.sendError(c, "too many keystrokes")
+		return
+	}
+
+	keystrokes := make([]typing.Keystroke, len(message.Keystrokes))
+	for i, k := range message.Keystrokes {
+		keystrokes[i] = typing.Keystroke{Char: k.C, OffsetMillis: k.T, Correct: k.OK}
+	}
+
+	if err := typing.ValidateKeystrokes(keystrokes); err != nil {
+		r.sendError(c, err.Error())
+		return
+	}
+
+	if len(keystrokes) > 0 && keystrokes[len(keystrokes)-1].OffsetMillis > int(now.Sub(r.startedAt)/time.Millisecond) {
+		r.sendError(c, "keystroke log is longer than the race has been running")
+		return
+	}
+
+	if p.position < r.snippetLength && !completesSnippet(r.spec.Snippet, keystrokes) {
+		r.sendError(c, "snippet is not finished")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.hub.ctx, storeTimeout)
+	defer cancel()
+
+	saved, err := r.hub.store.SaveResult(ctx, Result{
+		Room:            r.spec,
+		UserID:          c.participant.UserID,
+		WPM:             typing.CalculateWPM(r.snippetLength, seconds),
+		Accuracy:        typing.CalculateAccuracy(r.snippetLength, message.Errors),
+		Errors:          message.Errors,
+		DurationSeconds: seconds,
+		CompletedAt:     now,
+		Keystrokes:      keystrokes,
+	})
+
+	var rejected RejectedError
+	switch {
+	case errors.As(err, &rejected):
+		// A rejected run ends the participant's race without a place.
+		p.finished = true
+		r.sendError(c, "result rejected: "+strings.Join(rejected.Reasons, "; "))
+	case err != nil:
+		log.Printf("save race result failed for race %s: %v", r.spec.ID, err)
+		r.sendError(c, "failed to save result")
+		return
+	default:
+		r.places++
+		p.finished = true
+		p.position = r.snippetLength
+		p.result = &resultPayload{
+			Participant:        p.participant.ID,
+			Name:               p.participant.Name,
+			Place:              r.places,
+			WPM:                saved.WPM,
+			Accuracy:           saved.Accuracy,
+			Errors:             saved.Errors,
+			DurationSeconds:    saved.DurationSeconds,
+			HistoryID:          saved.HistoryID,
+			VerificationStatus: saved.VerificationStatus,
+		}
+		r.broadcast(finishedMessage{Type: "finished", Result: *p.result})
+	}
+
+	if r.allDone() {
+		r.end(storage.RaceFinished)
+	}
+}
+
+// reachable is the furthest position a typist at the plausible maximum speed can have reached by now.
+func (r *room) reachable(now time.Time) int {
+	elapsed := int(now.Sub(r.startedAt) / time.Millisecond)
+	return min(typing.DefaultLimits.MaxWPM*5*elapsed/60000, r.snippetLength)
+}
+
+// completesSnippet reports whether the keystroke log types the whole snippet: every keystroke
+// marked correct must be the snippet's next character, and those must cover all of it.
+func completesSnippet(snippet string, keystrokes []typing.Keystroke) bool {
+	text := []rune(snippet)
+	position := 0
+	for _, k := range keystrokes {
+		if !k.Correct {
+			continue
+		}
+
+		if position >= len(text) || string(text[position]) != k.Char {
+			return false
+		}
+		position++
+	}
+
+	return position == len(text)
+}
+
+// allDone reports whether every racer has finished or left.
+func (r *room) allDone() bool {
+	for _, p := range r.players {
+		if p.racing && !p.finished && p.connected {
+			return false
+		}
+	}
+
+	return true
+}
+
+// end stores the final status and broadcasts the results; run returns afterwards.
+func (r *room) end(status string) {
+	r.status = status
+	r.setStatus(status)
+	r.broadcast(resultsMessage{Type: "results", Status: status, Results: r.results()})
+}
+
+// close releases the room: it leaves the hub and disconnects every client.
+func (r *room) close() {
+	r.hub.remove(r.spec.ID)
+	close(r.done)
+
+	for id, c := range r.clients {
+		close(c.send)
+		delete(r.clients, id)
+	}
+
+	for _, timer := range []*time.Timer{r.countdown, r.deadline} {
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+func (r *room) setStatus(status string) {
+	ctx, cancel := context.WithTimeout(r.hub.ctx, storeTimeout)
+	defer cancel()
+
+	if err := r.hub.store.SetStatus(ctx, r.spec.ID, status); err != nil {
+		log.Printf("update status of race %s failed: %v", r.spec.ID, err)
+	}
+}
+
+func (r *room) state(you string) stateMessage {
+	state := stateMessage{
+		Type:   "state",
+		RaceID: r.spec.ID,
+		You:    you,
+		Status: r.status,
+		Snippet: snippetPayload{
+			ID:       r.spec.SnippetID,
+			Language: r.spec.Language,
+			Content:  r.spec.Snippet,
+		},
+		Players: make([]playerPayload, 0, len(r.order)),
+		Results: r.results(),
+	}
+
+	for _, id := range r.order {
+		state.Players = append(state.Players, r.playerPayload(r.players[id]))
+	}
+
+	if !r.startsAt.IsZero() {
+		state.StartsAt = formatTime(r.startsAt)
+	}
+
+	if !r.startedAt.IsZero() {
+		state.StartedAt = formatTime(r.startedAt)
+	}
+
+	return state
+}
+
+// results lists recorded results by place.
+func (r *room) results() []resultPayload {
+	results := make([]resultPayload, r.places)
+	for _, p := range r.players {
+		if p.result != nil {
+			results[p.result.Place-1] = *p.result
+		}
+	}
+
+	return results
+}
+
+func (r *room) playerPayload(p *player) playerPayload {
+	return playerPayload{
+		ID:        p.participant.ID,
+		Name:      p.participant.Name,
+		Owner:     p.participant.UserID == r.spec.OwnerID,
+		Connected: p.connected,
+		Racing:    p.racing,
+		Position:  p.position,
+		Errors:    p.errors,
+		Finished:  p.finished,
+	}
+}
+
+func (r *room) broadcast(message any) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("encode race message failed: %v", err)
+		return
+	}
+
+	for _, c := range r.clients {
+		r.enqueue(c, data)
+	}
+}
+
+func (r *room) sendTo(c *client, message any) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("encode race message failed: %v", err)
+		return
+	}
+
+	r.enqueue(c, data)
+}
+
+func (r *room) sendError(c *client, message string) {
+	r.sendTo(c, errorMessage{Type: "error", Message: message})
+}
+
+// enqueue delivers data without blocking the room; clients that cannot keep up are disconnected.
+func (r *room) enqueue(c *client, data []byte) {
+	select {
+	case c.send <- data:
+	default:
+		delete(r.clients, c.participant.ID)
+		close(c.send)
+		r.players[c.participant.ID].connected = false
+	}
+}
+
+func timerC(timer *time.Timer) <-chan time.Time {
+	if timer == nil {
+		return nil
+	}
+	return timer.C
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z07:00")
+}
@@ -0,0 +1,216 @@
+package races
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"code-type/backend/internal/storage"
+)
+
+// fakeStore records what a room persists.
+type fakeStore struct {
+	mu       sync.Mutex
+	statuses []string
+	results  []Result
+}
+
+func (s *fakeStore) SetStatus(_ context.Context, _, status string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statuses = append(s.statuses, status)
+	return nil
+}
+
+func (s *fakeStore) SaveResult(_ context.Context, result Result) (SavedResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.results = append(s.results, result)
+	return SavedResult{
+		HistoryID:          "h" + result.UserID,
+		WPM:                result.WPM,
+		Accuracy:           result.Accuracy,
+		Errors:             result.Errors,
+		DurationSeconds:    result.DurationSeconds,
+		VerificationStatus: "verified",
+	}, nil
+}
+
+// serverMessage is the union of the fields the test reads from room messages.
+type serverMessage struct {
+	Type        string        `json:"type"`
+	You         string        `json:"you"`
+	Status      string        `json:"status"`
+	Message     string        `json:"message"`
+	Participant string        `json:"participant"`
+	Position    int           `json:"position"`
+	Player      playerPayload `json:"player"`
+	Result      resultPayload `json:"result"`
+}
+
+// startRoom serves one room over WebSocket; the participant query parameter picks the caller.
+func startRoom(t *testing.T, spec Room, participants map[string]Participant) (*fakeStore, string) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	store := &fakeStore{}
+	hub := NewHub(ctx, store, Options{
+		Countdown:       50 * time.Millisecond,
+		MaxDuration:     time.Minute,
+		IdleTimeout:     time.Minute,
+		MaxParticipants: 8,
+		Lease:           time.Minute,
+	})
+	hub.Open(spec)
+
+	var upgrader websocket.Upgrader
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		participant, ok := participants[r.URL.Query().Get("participant")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+
+		if err := hub.Serve(spec.ID, participant, conn); err != nil {
+			conn.Close()
+		}
+	}))
+	t.Cleanup(func() {
+		cancel()
+		server.Close()
+	})
+
+	return store, "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func dial(t *testing.T, url, participant string) *websocket.Conn {
+	t.Helper()
+
+	conn, _, err := websocket.DefaultDialer.Dial(url+"?participant="+participant, nil)
+	if err != nil {
+		t.Fatalf("dial as %s: %v", participant, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+func send(t *testing.T, conn *websocket.Conn, message clientMessage) {
+	t.Helper()
+
+	if err := conn.WriteJSON(message); err != nil {
+		t.Fatalf("send %s: %v", message.Type, err)
+	}
+}
+
+// expect reads messages until one of the given type arrives, skipping the others.
+func expect(t *testing.T, conn *websocket.Conn, messageType string) serverMessage {
+	t.Helper()
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var message serverMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			t.Fatalf("waiting for %q: %v", messageType, err)
+		}
+
+		if message.Type == messageType {
+			return message
+		}
+	}
+}
+
+func TestRoomRunsARace(t *testing.T) {
+	spec := Room{ID: "race-1", OwnerID: "owner", Language: "go", Snippet: "fmt"}
+	store, url := startRoom(t, spec, map[string]Participant{
+		"owner": {ID: "p1", UserID: "owner", Name: "Owner"},
+		"guest": {ID: "p2", UserID: "guest", Name: "Guest"},
+	})
+
+	owner := dial(t, url, "owner")
+	if state := expect(t, owner, "state"); state.You != "p1" || state.Status != storage.RaceWaiting {
+		t.Fatalf("owner state = %+v, want p1 waiting", state)
+	}
+
+	guest := dial(t, url, "guest")
+	if state := expect(t, guest, "state"); state.You != "p2" {
+		t.Fatalf("guest state you = %q, want p2", state.You)
+	}
+	if joined := expect(t, owner, "joined"); joined.Player.ID != "p2" {
+		t.Fatalf("joined player = %q, want p2", joined.Player.ID)
+	}
+
+	send(t, guest, clientMessage{Type: clientStart})
+	if refusal := expect(t, guest, "error"); refusal.Message != "only the race owner can start the race" {
+		t.Fatalf("guest start error = %q", refusal.Message)
+	}
+
+	send(t, owner, clientMessage{Type: clientStart})
+	for _, conn := range []*websocket.Conn{owner, guest} {
+		expect(t, conn, "countdown")
+		expect(t, conn, "start")
+	}
+
+	send(t, guest, clientMessage{Type: clientFinish})
+	if refusal := expect(t, guest, "error"); refusal.Message != "snippet is not finished" {
+		t.Fatalf("early finish error = %q, want snippet is not finished", refusal.Message)
+	}
+
+	// At the plausible maximum speed three characters are reachable after 120ms.
+	time.Sleep(200 * time.Millisecond)
+	send(t, guest, clientMessage{Type: clientProgress, Position: 3, Errors: 1})
+	if progress := expect(t, owner, "progress"); progress.Participant != "p2" || progress.Position != 3 {
+		t.Fatalf("progress = %+v, want p2 at 3", progress)
+	}
+
+	send(t, guest, clientMessage{Type: clientFinish, Errors: 1})
+	for _, conn := range []*websocket.Conn{owner, guest} {
+		if finished := expect(t, conn, "finished"); finished.Result.Participant != "p2" || finished.Result.Place != 1 {
+			t.Fatalf("guest result = %+v, want p2 in first place", finished.Result)
+		}
+	}
+
+	send(t, owner, clientMessage{Type: clientFinish, Keystrokes: []keystrokePayload{
+		{C: "f", T: 40, OK: true},
+		{C: "m", T: 80, OK: true},
+		{C: "t", T: 120, OK: true},
+	}})
+	if finished := expect(t, guest, "finished"); finished.Result.Participant != "p1" || finished.Result.Place != 2 {
+		t.Fatalf("owner result = %+v, want p1 in second place", finished.Result)
+	}
+	if results := expect(t, guest, "results"); results.Status != storage.RaceFinished {
+		t.Fatalf("results status = %q, want finished", results.Status)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	wantStatuses := []string{storage.RaceCountdown, storage.RaceRunning, storage.RaceFinished}
+	if !slices.Equal(store.statuses, wantStatuses) {
+		t.Fatalf("statuses = %v, want %v", store.statuses, wantStatuses)
+	}
+
+	if len(store.results) != 2 {
+		t.Fatalf("saved %d results, want 2", len(store.results))
+	}
+	if guestResult := store.results[0]; guestResult.UserID != "guest" || guestResult.Errors != 1 || guestResult.DurationSeconds < 1 {
+		t.Fatalf("guest result = %+v", guestResult)
+	}
+	if ownerResult := store.results[1]; ownerResult.UserID != "owner" || len(ownerResult.Keystrokes) != 3 {
+		t.Fatalf("owner result = %+v", ownerResult)
+	}
+}
@@ -0,0 +1,359 @@
+package races
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"code-type/backend/internal/joincode"
+	"code-type/backend/internal/storage"
+	"code-type/backend/internal/typing"
+)
+
+const (
+	codeLength   = 6
+	codeAttempts = 5
+)
+
+// ErrSnippetNotFound is returned when creating a race for an unknown snippet or language.
+var ErrSnippetNotFound = errors.New("snippet not found")
+
+// MisroutedError is returned when connecting to a race whose room runs on another instance.
+// Clients reach the room by retrying through InstanceID.
+type MisroutedError struct {
+	InstanceID string
+}
+
+func (e MisroutedError) Error() string {
+	return "race room runs on instance " + e.InstanceID
+}
+
+// Details is a race as seen by one of its participants.
+type Details struct {
+	Race         storage.Race
+	You          string
+	Participants []Participant
+	Results      []ParticipantResult
+}
+
+// ParticipantResult is a stored race result labelled with the public participant ID.
+type ParticipantResult struct {
+	Participant string
+	Name        string
+	storage.RaceResult
+}
+
+// Service creates and joins races and runs their rooms.
+// Races are leased to the instance that runs their rooms; see RunLeases.
+type Service struct {
+	repo       *storage.RaceRepository
+	snippets   *storage.SnippetRepository
+	hub        *Hub
+	instanceID string
+	opts       Options
+}
+
+// NewService creates a race service whose rooms stop when ctx is cancelled.
+// Finished runs are written to history through the given repository.
+// instanceID identifies this backend instance among replicas sharing the database.
+func NewService(
+	ctx context.Context,
+	repo *storage.RaceRepository,
+	snippets *storage.SnippetRepository,
+	history *storage.HistoryRepository,
+	instanceID string,
+	opts Options,
+) *Service {
+	return &Service{
+		repo:       repo,
+		snippets:   snippets,
+		hub:        NewHub(ctx, &historyStore{races: repo, history: history}, opts),
+		instanceID: instanceID,
+		opts:       opts,
+	}
+}
+
+// RunLeases renews the leases of this instance's races and expires races whose instance
+// stopped renewing them, until ctx is cancelled.
+func (s *Service) RunLeases(ctx context.Context) {
+	// Renewing three times per lease keeps a single failed heartbeat from expiring live races.
+	ticker := time.NewTicker(s.opts.Lease / 3)
+	defer ticker.Stop()
+
+	for {
+		if err := s.repo.Heartbeat(ctx, s.instanceID); err != nil && ctx.Err() == nil {
+			log.Printf("renew race leases failed: %v", err)
+		}
+
+		if err := s.repo.ExpireStale(ctx, s.opts.Lease); err != nil && ctx.Err() == nil {
+			log.Printf("expire stale races failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Create opens a race on a catalog snippet: the given one, or a random one in the language.
+func (s *Service) Create(ctx context.Context, userID, snippetID, language string) (Details, error) {
+	var (
+		snippet storage.Snippet
+		err     error
+	)
+	if snippetID != "" {
+		snippet, err = s.snippets.GetByID(ctx, snippetID)
+	} else {
+		snippet, err = s.snippets.Random(ctx, storage.SnippetFilter{Language: language})
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		return Details{}, ErrSnippetNotFound
+	}
+	if err != nil {
+		return Details{}, fmt.Errorf("load snippet: %w", err)
+	}
+
+	params := storage.CreateRaceParams{
+		OwnerID:     userID,
+		SnippetID:   snippet.ID,
+		SnippetHash: storage.ContentHash(snippet.Content),
+		Language:    snippet.Language,
+		InstanceID:  s.instanceID,
+	}
+
+	var race storage.Race
+	for attempt := 0; ; attempt++ {
+		if params.Code, err = joincode.New(codeLength); err != nil {
+			return Details{}, err
+		}
+
+		race, err = s.repo.Create(ctx, params)
+		if errors.Is(err, storage.ErrConflict) && attempt < codeAttempts {
+			continue
+		}
+		if err != nil {
+			return Details{}, fmt.Errorf("create race: %w", err)
+		}
+		break
+	}
+
+	s.hub.Open(Room{
+		ID:          race.ID,
+		OwnerID:     race.OwnerID,
+		Language:    race.Language,
+		SnippetID:   race.SnippetID,
+		SnippetHash: race.SnippetHash,
+		Snippet:     strings.ReplaceAll(snippet.Content, "\r\n", "\n"),
+	})
+
+	return s.Get(ctx, userID, race.ID)
+}
+
+// Join adds the user to the race with the join code. Any instance can join a race, since
+// participants live in the database; the race's InstanceID tells the client where its room runs.
+// Returns storage.ErrNotFound for unknown codes and storage.ErrRaceClosed for races that cannot be joined.
+func (s *Service) Join(ctx context.Context, userID, code string) (Details, error) {
+	race, err := s.repo.GetByCode(ctx, strings.ToUpper(strings.TrimSpace(code)))
+	if err != nil {
+		return Details{}, err
+	}
+
+	if race.Status != storage.RaceWaiting || !s.leased(race) {
+		return Details{}, storage.ErrRaceClosed
+	}
+
+	if err := s.repo.AddParticipant(ctx, race.ID, userID, s.opts.MaxParticipants); err != nil {
+		return Details{}, err
+	}
+
+	return s.Get(ctx, userID, race.ID)
+}
+
+// Get returns the race if the user participates in it; otherwise storage.ErrNotFound.
+func (s *Service) Get(ctx context.Context, userID, raceID string) (Details, error) {
+	race, err := s.repo.GetByID(ctx, raceID)
+	if err != nil {
+		return Details{}, err
+	}
+
+	participants, you, err := s.participants(ctx, raceID, userID)
+	if err != nil {
+		return Details{}, err
+	}
+
+	results, err := s.repo.Results(ctx, raceID)
+	if err != nil {
+		return Details{}, err
+	}
+
+	byUser := make(map[string]Participant, len(participants))
+	for _, participant := range participants {
+		byUser[participant.UserID] = participant
+	}
+
+	details := Details{
+		Race:         race,
+		You:          you.ID,
+		Participants: participants,
+		Results:      make([]ParticipantResult, 0, len(results)),
+	}
+	for _, result := range results {
+		participant := byUser[result.UserID]
+		details.Results = append(details.Results, ParticipantResult{
+			Participant: participant.ID,
+			Name:        participant.Name,
+			RaceResult:  result,
+		})
+	}
+
+	return details, nil
+}
+
+// Participant returns the caller's identity in an open race room running on this instance.
+// Returns storage.ErrNotFound for non-participants, ErrRoomClosed when the room is gone and
+// MisroutedError when the room runs on another instance.
+func (s *Service) Participant(ctx context.Context, userID, raceID string) (Participant, error) {
+	race, err := s.repo.GetByID(ctx, raceID)
+	if err != nil {
+		return Participant{}, err
+	}
+
+	_, you, err := s.participants(ctx, raceID, userID)
+	if err != nil {
+		return Participant{}, err
+	}
+
+	if !race.Open() || !s.leased(race) {
+		return Participant{}, ErrRoomClosed
+	}
+
+	if race.InstanceID != s.instanceID {
+		return Participant{}, MisroutedError{InstanceID: race.InstanceID}
+	}
+
+	if !s.hub.IsOpen(raceID) {
+		return Participant{}, ErrRoomClosed
+	}
+
+	return you, nil
+}
+
+// Serve attaches an upgraded connection to the race room and blocks until it disconnects.
+func (s *Service) Serve(raceID string, participant Participant, conn *websocket.Conn) error {
+	return s.hub.Serve(raceID, participant, conn)
+}
+
+// leased reports whether the race's instance still renews its lease. Rooms on this instance
+// are checked in the hub instead, so the lease only matters for other instances' races.
+func (s *Service) leased(race storage.Race) bool {
+	if race.InstanceID == s.instanceID {
+		return s.hub.IsOpen(race.ID)
+	}
+
+	return time.Since(race.HeartbeatAt) < s.opts.Lease
+}
+
+// participants numbers participants in join order (p1, p2, ...) and finds the caller among them.
+func (s *Service) participants(ctx context.Context, raceID, userID string) ([]Participant, Participant, error) {
+	rows, err := s.repo.Participants(ctx, raceID)
+	if err != nil {
+		return nil, Participant{}, err
+	}
+
+	var you Participant
+	participants := make([]Participant, len(rows))
+	for i, row := range rows {
+		participants[i] = Participant{
+			ID:     "p" + strconv.Itoa(i+1),
+			UserID: row.UserID,
+			Name:   row.DisplayName,
+		}
+		if participants[i].Name == "" {
+			participants[i].Name = "Player " + strconv.Itoa(i+1)
+		}
+
+		if row.UserID == userID {
+			you = participants[i]
+		}
+	}
+
+	if you.ID == "" {
+		return nil, Participant{}, storage.ErrNotFound
+	}
+
+	return participants, you, nil
+}
+
+// historyStore records room events in the races table and practice history.
+type historyStore struct {
+	races   *storage.RaceRepository
+	history *storage.HistoryRepository
+}
+
+func (s *historyStore) SetStatus(ctx context.Context, raceID, status string) error {
+	return s.races.SetStatus(ctx, raceID, status)
+}
+
+// SaveResult verifies the run like a regular history submission and stores it with the race reference.
+// Speed and time stay the ones measured by the server's clock; the keystroke log only refines
+// accuracy and errors, and is flagged when its span does not match the server's time.
+func (s *historyStore) SaveResult(ctx context.Context, result Result) (SavedResult, error) {
+	verdict := typing.Verify(typing.Run{
+		WPM:             result.WPM,
+		Accuracy:        result.Accuracy,
+		Errors:          result.Errors,
+		DurationSeconds: result.DurationSeconds,
+		ServerTimed:     true,
+		Snippet:         result.Room.Snippet,
+		Keystrokes:      result.Keystrokes,
+	}, typing.DefaultLimits)
+	if verdict.Rejected {
+		return SavedResult{}, RejectedError{Reasons: verdict.Reasons}
+	}
+
+	params := storage.CreateHistoryParams{
+		UserID:             result.UserID,
+		Language:           result.Room.Language,
+		SnippetID:          result.Room.SnippetID,
+		SnippetHash:        result.Room.SnippetHash,
+		RaceID:             result.Room.ID,
+		WPM:                result.WPM,
+		Accuracy:           result.Accuracy,
+		Errors:             result.Errors,
+		DurationSeconds:    result.DurationSeconds,
+		CompletedAt:        result.CompletedAt,
+		Keystrokes:         result.Keystrokes,
+		VerificationStatus: verdict.Status,
+	}
+
+	if verdict.Recomputed {
+		params.Accuracy = verdict.Accuracy
+		params.Errors = verdict.Errors
+	}
+
+	if len(result.Keystrokes) > 0 {
+		params.KeyStats, params.BigramStats = typing.Summarize(result.Room.Snippet, result.Keystrokes)
+	}
+
+	entry, err := s.history.Create(ctx, params)
+	if err != nil {
+		return SavedResult{}, fmt.Errorf("save race result: %w", err)
+	}
+
+	return SavedResult{
+		HistoryID:          entry.ID,
+		WPM:                entry.WPM,
+		Accuracy:           entry.Accuracy,
+		Errors:             entry.Errors,
+		DurationSeconds:    entry.DurationSeconds,
+		VerificationStatus: entry.VerificationStatus,
+	}, nil
+}
@@ -0,0 +1,444 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Account deletion statuses.
+const (
+	AccountDeletionPending   = "pending"
+	AccountDeletionCompleted = "completed"
+	AccountDeletionRestored  = "restored"
+)
+
+// AccountDeletion is a persisted request to delete a user's account.
+// The Kratos identity is deactivated first (DeactivatedAt) and the deletion can be restored
+// until PurgeAfter. After that IdentityDeletedAt is set once the identity is gone; the
+// application data is purged last, which completes the deletion.
+type AccountDeletion struct {
+	ID                string
+	UserID            string
+	Status            string
+	Attempts          int
+	LastError         string
+	NextAttemptAt     time.Time
+	RequestedAt       time.Time
+	PurgeAfter        time.Time
+	DeactivatedAt     *time.Time
+	IdentityDeletedAt *time.Time
+	RestoredAt        *time.Time
+	CompletedAt       *time.Time
+}
+
+const accountDeletionColumns = `id, user_id, status, attempts, COALESCE(last_error, ''), next_attempt_at, requested_at, purge_after, deactivated_at, identity_deleted_at, restored_at, completed_at`
+
+// AccountDeletionRepository stores the account deletion workflow and purges user data.
+type AccountDeletionRepository struct {
+	db *sql.DB
+}
+
+// NewAccountDeletionRepository creates a new AccountDeletionRepository.
+func NewAccountDeletionRepository(db *sql.DB) *AccountDeletionRepository {
+	return &AccountDeletionRepository{db: db}
+}
+
+// Request records a deletion request for the user that is purged once grace has passed,
+// or returns the user's pending one, keeping its original purge time. restoreTokenHash
+// replaces the stored hash unless the grace period has already been closed by the worker.
+// Either way the request is claimed for lease, so the background worker leaves it alone
+// while the caller processes it.
+func (r *AccountDeletionRepository) Request(ctx context.Context, userID, restoreTokenHash string, grace, lease time.Duration) (AccountDeletion, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return AccountDeletion{}, fmt.Errorf("begin request deletion transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// xmax is zero only for freshly inserted rows, which tells a new request from a repeated one.
+	query := `
+		INSERT INTO account_deletions (user_id, attempts, next_attempt_at, purge_after, restore_token_hash)
+		VALUES ($1, 1, NOW() + make_interval(secs => $2), NOW() + make_interval(secs => $3), $4)
+		ON CONFLICT (user_id) WHERE status = 'pending'
+		DO UPDATE SET
+			attempts = account_deletions.attempts + 1,
+			next_attempt_at = EXCLUDED.next_attempt_at,
+			restore_token_hash = CASE
+				WHEN account_deletions.restore_token_hash IS NULL THEN NULL
+				ELSE EXCLUDED.restore_token_hash
+			END
+		RETURNING ` + accountDeletionColumns + `, xmax = 0;
+	`
+
+	var inserted bool
+	row := tx.QueryRowContext(ctx, query, userID, lease.Seconds(), grace.Seconds(), restoreTokenHash)
+	deletion, err := scanAccountDeletion(extendedRow{row: row, extra: []any{&inserted}})
+	if err != nil {
+		return AccountDeletion{}, fmt.Errorf("insert account deletion: %w", err)
+	}
+
+	if inserted {
+		if err := insertDeletionAudit(ctx, tx, deletion, "requested", ""); err != nil {
+			return AccountDeletion{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return AccountDeletion{}, fmt.Errorf("commit account deletion request: %w", err)
+	}
+
+	return deletion, nil
+}
+
+// ClaimDue claims up to limit pending deletions whose next attempt is due, pushing their next
+// attempt lease into the future. Rows claimed by another replica are skipped, not waited for.
+func (r *AccountDeletionRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]AccountDeletion, error) {
+	query := `
+		UPDATE account_deletions
+		SET attempts = attempts + 1, next_attempt_at = NOW() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id
+			FROM account_deletions
+			WHERE status = 'pending' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + accountDeletionColumns + `;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("claim due account deletions: %w", err)
+	}
+	defer rows.Close()
+
+	var deletions []AccountDeletion
+	for rows.Next() {
+		deletion, err := scanAccountDeletion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan account deletion: %w", err)
+		}
+
+		deletions = append(deletions, deletion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate account deletions: %w", err)
+	}
+
+	return deletions, nil
+}
+
+// Deactivate runs deactivate for the user of a pending deletion and records that the account
+// is deactivated. The deletion stays locked meanwhile, so a concurrent restore cannot be undone
+// by a late deactivation. Returns ErrNotFound when the deletion is no longer pending.
+func (r *AccountDeletionRepository) Deactivate(ctx context.Context, deletionID string, deactivate func(ctx context.Context, userID string) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin deactivate transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deletion, err := lockPendingDeletion(ctx, tx, "id = $1", deletionID)
+	if err != nil {
+		return err
+	}
+
+	if deletion.DeactivatedAt == nil {
+		if err := deactivate(ctx, deletion.UserID); err != nil {
+			return err
+		}
+
+		const query = `
+			UPDATE account_deletions
+			SET deactivated_at = NOW()
+			WHERE id = $1;
+		`
+
+		if _, err := tx.ExecContext(ctx, query, deletionID); err != nil {
+			return fmt.Errorf("mark account deactivated: %w", err)
+		}
+
+		if err := insertDeletionAudit(ctx, tx, deletion, "deactivated", ""); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit deactivation: %w", err)
+	}
+
+	return nil
+}
+
+// DeferUntilPurge reports whether a pending deletion is still in its grace period, and if so
+// schedules its next attempt for when the period ends. Once the period is over the restore token
+// is cleared in the same statement, so a restore racing with the hard deletion cannot succeed.
+// Returns ErrNotFound when the deletion is no longer pending.
+func (r *AccountDeletionRepository) DeferUntilPurge(ctx context.Context, deletionID string) (bool, error) {
+	const query = `
+		UPDATE account_deletions
+		SET
+			next_attempt_at = CASE WHEN purge_after > NOW() THEN purge_after ELSE next_attempt_at END,
+			restore_token_hash = CASE WHEN purge_after > NOW() THEN restore_token_hash END
+		WHERE id = $1 AND status = 'pending'
+		RETURNING purge_after > NOW();
+	`
+
+	var deferred bool
+	err := r.db.QueryRowContext(ctx, query, deletionID).Scan(&deferred)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, ErrNotFound
+	}
+	if err != nil {
+		return false, fmt.Errorf("defer account deletion: %w", err)
+	}
+
+	return deferred, nil
+}
+
+// Restore cancels the pending deletion whose restore token hashes to tokenHash, provided its
+// grace period has not ended. activate runs while the deletion is locked and must succeed for
+// the restore to be recorded. Returns ErrNotFound for unknown, expired or finished deletions.
+func (r *AccountDeletionRepository) Restore(ctx context.Context, tokenHash string, activate func(ctx context.Context, userID string) error) (AccountDeletion, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return AccountDeletion{}, fmt.Errorf("begin restore transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deletion, err := lockPendingDeletion(ctx, tx, "restore_token_hash = $1 AND purge_after > NOW()", tokenHash)
+	if err != nil {
+		return AccountDeletion{}, err
+	}
+
+	if err := activate(ctx, deletion.UserID); err != nil {
+		return AccountDeletion{}, err
+	}
+
+	query := `
+		UPDATE account_deletions
+		SET status = 'restored', restored_at = NOW(), restore_token_hash = NULL, last_error = NULL
+		WHERE id = $1
+		RETURNING ` + accountDeletionColumns + `;
+	`
+
+	restored, err := scanAccountDeletion(tx.QueryRowContext(ctx, query, deletion.ID))
+	if err != nil {
+		return AccountDeletion{}, fmt.Errorf("restore account deletion: %w", err)
+	}
+
+	if err := insertDeletionAudit(ctx, tx, restored, "restored", ""); err != nil {
+		return AccountDeletion{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return AccountDeletion{}, fmt.Errorf("commit restore: %w", err)
+	}
+
+	return restored, nil
+}
+
+// MarkIdentityDeleted records that the deletion's Kratos identity is gone. Marking it again is a no-op.
+func (r *AccountDeletionRepository) MarkIdentityDeleted(ctx context.Context, deletionID string) error {
+	const query = `
+		WITH marked AS (
+			UPDATE account_deletions
+			SET identity_deleted_at = NOW()
+			WHERE id = $1 AND identity_deleted_at IS NULL
+			RETURNING id, user_id
+		)
+		INSERT INTO account_deletion_audit (deletion_id, user_id, event)
+		SELECT id, user_id, 'identity_deleted'
+		FROM marked;
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, deletionID); err != nil {
+		return fmt.Errorf("mark identity deleted: %w", err)
+	}
+
+	return nil
+}
+
+// RecordFailure stores the error of a failed attempt in the deletion and its audit trail,
+// and schedules the next attempt after retryIn.
+func (r *AccountDeletionRepository) RecordFailure(ctx context.Context, deletionID string, cause error, retryIn time.Duration) error {
+	const query = `
+		WITH failed AS (
+			UPDATE account_deletions
+			SET last_error = $2, next_attempt_at = NOW() + make_interval(secs => $3)
+			WHERE id = $1 AND status = 'pending'
+			RETURNING id, user_id
+		)
+		INSERT INTO account_deletion_audit (deletion_id, user_id, event, detail)
+		SELECT id, user_id, 'failed', $2
+		FROM failed;
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, deletionID, cause.Error(), retryIn.Seconds()); err != nil {
+		return fmt.Errorf("record account deletion failure: %w", err)
+	}
+
+	return nil
+}
+
+// Purge deletes every per-user record of the deletion's user and completes the deletion,
+// all in one transaction: either nothing is removed or the data is gone and the final audit entry
+// is written. Purging a completed deletion is a no-op. Returns ErrNotFound for unknown or
+// restored deletions.
+func (r *AccountDeletionRepository) Purge(ctx context.Context, deletionID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin purge transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT ` + accountDeletionColumns + `
+		FROM account_deletions
+		WHERE id = $1
+		FOR UPDATE;
+	`
+
+	deletion, err := scanAccountDeletion(tx.QueryRowContext(ctx, query, deletionID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("lock account deletion: %w", err)
+	}
+
+	if deletion.Status == AccountDeletionCompleted {
+		return nil
+	}
+	if deletion.Status == AccountDeletionRestored {
+		return ErrNotFound
+	}
+
+	if err := purgeUserData(ctx, tx, deletion.UserID); err != nil {
+		return err
+	}
+
+	const completeQuery = `
+		UPDATE account_deletions
+		SET status = 'completed', completed_at = NOW(), last_error = NULL
+		WHERE id = $1;
+	`
+
+	if _, err := tx.ExecContext(ctx, completeQuery, deletionID); err != nil {
+		return fmt.Errorf("complete account deletion: %w", err)
+	}
+
+	if err := insertDeletionAudit(ctx, tx, deletion, "completed", ""); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit purge: %w", err)
+	}
+
+	return nil
+}
+
+// purgeUserData removes the user's rows from every per-user table. Teams go first so
+// ownership is handed over while the memberships still exist; the history goes last and
+// takes keystroke logs, statistics and idempotency keys with it.
+func purgeUserData(ctx context.Context, tx dbtx, userID string) error {
+	purges := []func(context.Context, dbtx, string) error{
+		deleteTeamMembershipsByUser,
+		deleteChallengeStandingsByUser,
+		deleteRacesByUser,
+		deleteAchievementsByUser,
+		deleteGoalsByUser,
+		deletePreferencesByUser,
+		deleteProfileByUser,
+		deleteHistoryByUser,
+	}
+
+	for _, purge := range purges {
+		if err := purge(ctx, tx, userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lockPendingDeletion locks the pending deletion matching condition, whose only parameter is arg.
+func lockPendingDeletion(ctx context.Context, tx dbtx, condition string, arg any) (AccountDeletion, error) {
+	query := `
+		SELECT ` + accountDeletionColumns + `
+		FROM account_deletions
+		WHERE status = 'pending' AND ` + condition + `
+		FOR UPDATE;
+	`
+
+	deletion, err := scanAccountDeletion(tx.QueryRowContext(ctx, query, arg))
+	if errors.Is(err, sql.ErrNoRows) {
+		return AccountDeletion{}, ErrNotFound
+	}
+	if err != nil {
+		return AccountDeletion{}, fmt.Errorf("lock account deletion: %w", err)
+	}
+
+	return deletion, nil
+}
+
+func insertDeletionAudit(ctx context.Context, tx dbtx, deletion AccountDeletion, event, detail string) error {
+	const query = `
+		INSERT INTO account_deletion_audit (deletion_id, user_id, event, detail)
+		VALUES ($1, $2, $3, $4);
+	`
+
+	if _, err := tx.ExecContext(ctx, query, deletion.ID, deletion.UserID, event, detail); err != nil {
+		return fmt.Errorf("insert %s audit entry: %w", event, err)
+	}
+
+	return nil
+}
+
+func scanAccountDeletion(row rowScanner) (AccountDeletion, error) {
+	var (
+		deletion          AccountDeletion
+		deactivatedAt     sql.NullTime
+		identityDeletedAt sql.NullTime
+		restoredAt        sql.NullTime
+		completedAt       sql.NullTime
+	)
+
+	if err := row.Scan(
+		&deletion.ID,
+		&deletion.UserID,
+		&deletion.Status,
+		&deletion.Attempts,
+		&deletion.LastError,
+		&deletion.NextAttemptAt,
+		&deletion.RequestedAt,
+		&deletion.PurgeAfter,
+		&deactivatedAt,
+		&identityDeletedAt,
+		&restoredAt,
+		&completedAt,
+	); err != nil {
+		return AccountDeletion{}, err
+	}
+
+	if deactivatedAt.Valid {
+		deletion.DeactivatedAt = &deactivatedAt.Time
+	}
+	if identityDeletedAt.Valid {
+		deletion.IdentityDeletedAt = &identityDeletedAt.Time
+	}
+	if restoredAt.Valid {
+		deletion.RestoredAt = &restoredAt.Time
+	}
+	if completedAt.Valid {
+		deletion.CompletedAt = &completedAt.Time
+	}
+
+	return deletion, nil
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// UnlockedAchievement records when a user earned an achievement.
+// HistoryID is empty when the triggering run has since been deleted.
+type UnlockedAchievement struct {
+	AchievementID string
+	HistoryID     string
+	UnlockedAt    time.Time
+}
+
+// AchievementMetrics are the lifetime totals achievement rules are evaluated against.
+type AchievementMetrics struct {
+	Runs         int
+	TotalSeconds int
+	TotalErrors  int
+	BestWPM      int
+	BestAccuracy int
+}
+
+// AchievementRepository handles persistence of unlocked achievements.
+type AchievementRepository struct {
+	db *sql.DB
+}
+
+// NewAchievementRepository creates a new AchievementRepository.
+func NewAchievementRepository(db *sql.DB) *AchievementRepository {
+	return &AchievementRepository{db: db}
+}
+
+// ListByUser returns the user's unlocked achievements, oldest first.
+func (r *AchievementRepository) ListByUser(ctx context.Context, userID string) ([]UnlockedAchievement, error) {
+	const query = `
+		SELECT achievement_id, history_id, unlocked_at
+		FROM user_achievements
+		WHERE user_id = $1
+		ORDER BY unlocked_at, achievement_id;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query achievements: %w", err)
+	}
+	defer rows.Close()
+
+	achievements := make([]UnlockedAchievement, 0)
+	for rows.Next() {
+		var (
+			achievement UnlockedAchievement
+			historyID   sql.NullString
+		)
+		if err := rows.Scan(&achievement.AchievementID, &historyID, &achievement.UnlockedAt); err != nil {
+			return nil, fmt.Errorf("scan achievement: %w", err)
+		}
+
+		achievement.HistoryID = historyID.String
+		achievements = append(achievements, achievement)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate achievements: %w", err)
+	}
+
+	return achievements, nil
+}
+
+// Unlock records an achievement for the user. It reports false if the achievement was already unlocked.
+func (r *AchievementRepository) Unlock(ctx context.Context, userID, achievementID, historyID string) (bool, error) {
+	const query = `
+		INSERT INTO user_achievements (user_id, achievement_id, history_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, achievement_id) DO NOTHING;
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, achievementID, nullString(historyID))
+	if err != nil {
+		return false, fmt.Errorf("unlock achievement: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("read unlocked achievement count: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+// Metrics returns the user's lifetime totals, optionally restricted to a language.
+func (r *AchievementRepository) Metrics(ctx context.Context, userID, language string) (AchievementMetrics, error) {
+	const query = `
+		SELECT COUNT(*)::int,
+		       COALESCE(SUM(duration_seconds), 0)::int,
+		       COALESCE(SUM(errors), 0)::int,
+		       COALESCE(MAX(wpm), 0),
+		       COALESCE(MAX(accuracy), 0)
+		FROM practice_history
+		WHERE user_id = $1 AND ($2 = '' OR language = $2);
+	`
+
+	var metrics AchievementMetrics
+	err := r.db.QueryRowContext(ctx, query, userID, language).Scan(
+		&metrics.Runs,
+		&metrics.TotalSeconds,
+		&metrics.TotalErrors,
+		&metrics.BestWPM,
+		&metrics.BestAccuracy,
+	)
+	if err != nil {
+		return AchievementMetrics{}, fmt.Errorf("query achievement metrics: %w", err)
+	}
+
+	return metrics, nil
+}
+
+// deleteAchievementsByUser removes all achievements of the user.
+func deleteAchievementsByUser(ctx context.Context, tx dbtx, userID string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_achievements WHERE user_id = $1;`, userID); err != nil {
+		return fmt.Errorf("delete achievements: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// KeyAnalytics aggregates a user's attempts at a single character across runs.
+type KeyAnalytics struct {
+	Key               string
+	Presses           int
+	Errors            int
+	AverageLatencyMs  float64
+	LatencySampleSize int
+}
+
+// BigramAnalytics aggregates a user's clean transitions between two characters across runs.
+type BigramAnalytics struct {
+	Bigram           string
+	Occurrences      int
+	AverageLatencyMs float64
+}
+
+// AnalyticsRepository reads keystroke aggregates stored alongside history entries.
+type AnalyticsRepository struct {
+	db *sql.DB
+}
+
+// NewAnalyticsRepository creates a new AnalyticsRepository.
+func NewAnalyticsRepository(db *sql.DB) *AnalyticsRepository {
+	return &AnalyticsRepository{db: db}
+}
+
+// KeyStats returns per-key totals for the user, optionally restricted to a language.
+// Keys are ordered by error count, then by average latency, both descending.
+func (r *AnalyticsRepository) KeyStats(ctx context.Context, userID, language string) ([]KeyAnalytics, error) {
+	const query = `
+		SELECT key,
+		       SUM(presses)::int,
+		       SUM(errors)::int,
+		       COALESCE(ROUND(SUM(latency_ms_total)::numeric / NULLIF(SUM(latency_samples), 0), 1), 0)::float8,
+		       SUM(latency_samples)::int
+		FROM history_key_stats
+		WHERE user_id = $1 AND ($2 = '' OR language = $2)
+		GROUP BY key
+		ORDER BY 3 DESC, 4 DESC, key;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, language)
+	if err != nil {
+		return nil, fmt.Errorf("query key stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make([]KeyAnalytics, 0)
+	for rows.Next() {
+		var stat KeyAnalytics
+		if err := rows.Scan(&stat.Key, &stat.Presses, &stat.Errors, &stat.AverageLatencyMs, &stat.LatencySampleSize); err != nil {
+			return nil, fmt.Errorf("scan key stats: %w", err)
+		}
+
+		stats = append(stats, stat)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate key stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// SlowestBigrams returns the bigrams with the highest average latency for the user.
+// Bigrams seen fewer than minOccurrences times are skipped to avoid noise.
+func (r *AnalyticsRepository) SlowestBigrams(ctx context.Context, userID, language string, minOccurrences, limit int) ([]BigramAnalytics, error) {
+	const query = `
+		SELECT bigram,
+		       SUM(occurrences)::int,
+		       ROUND(SUM(latency_ms_total)::numeric / SUM(occurrences), 1)::float8
+		FROM history_bigram_stats
+		WHERE user_id = $1 AND ($2 = '' OR language = $2)
+		GROUP BY bigram
+		HAVING SUM(occurrences) >= $3
+		ORDER BY 3 DESC, 2 DESC, bigram
+		LIMIT $4;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, language, minOccurrences, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query bigram stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := make([]BigramAnalytics, 0)
+	for rows.Next() {
+		var stat BigramAnalytics
+		if err := rows.Scan(&stat.Bigram, &stat.Occurrences, &stat.AverageLatencyMs); err != nil {
+			return nil, fmt.Errorf("scan bigram stats: %w", err)
+		}
+
+		stats = append(stats, stat)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate bigram stats: %w", err)
+	}
+
+	return stats, nil
+}
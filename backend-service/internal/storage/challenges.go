@@ -0,0 +1,423 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Challenge statuses. The scheduler opens a challenge at its start and closes it at its end.
+const (
+	ChallengeScheduled = "scheduled"
+	ChallengeOpen      = "open"
+	ChallengeClosed    = "closed"
+)
+
+// challengeScheduleLock is the advisory lock key that lets only one replica run the schedule at a time.
+const challengeScheduleLock = 7_231_002
+
+// Challenge is a timeboxed competition between the members of a team on a set of snippets.
+type Challenge struct {
+	ID        string
+	TeamID    string
+	Name      string
+	StartsAt  time.Time
+	EndsAt    time.Time
+	Status    string
+	CreatedAt time.Time
+	OpenedAt  *time.Time
+	ClosedAt  *time.Time
+	Snippets  []ChallengeSnippet
+}
+
+// ChallengeSnippet is a snippet runs must be typed on to count toward a challenge.
+// SnippetID is empty when the catalog snippet was deleted after the challenge was created.
+type ChallengeSnippet struct {
+	SnippetID   string
+	SnippetHash string
+	Language    string
+}
+
+// CreateChallengeParams contains parameters to schedule a challenge.
+type CreateChallengeParams struct {
+	TeamID   string
+	Name     string
+	StartsAt time.Time
+	EndsAt   time.Time
+	Snippets []ChallengeSnippet
+}
+
+// ChallengeStanding is a member's position in a challenge. Score is the sum of the member's best
+// verified WPM on each challenge snippet; DisplayName already falls back to "Member N".
+type ChallengeStanding struct {
+	Rank              int
+	MemberID          string
+	UserID            string
+	DisplayName       string
+	Score             int
+	SnippetsCompleted int
+	AverageAccuracy   float64
+	LastCompletedAt   time.Time
+}
+
+// ChallengeRepository handles persistence of team challenges and their standings.
+type ChallengeRepository struct {
+	db *sql.DB
+}
+
+// NewChallengeRepository creates a new ChallengeRepository.
+func NewChallengeRepository(db *sql.DB) *ChallengeRepository {
+	return &ChallengeRepository{db: db}
+}
+
+const challengeColumns = `id, team_id, name, starts_at, ends_at, status, created_at, opened_at, closed_at`
+
+// challengeScoresCTE ranks the members of team $1 in challenge $2 by their best verified run on
+// each challenge snippet inside the window. Members without a qualifying run are left out.
+const challengeScoresCTE = teamMembersCTE + `,
+	best AS (
+		SELECT DISTINCT ON (m.id, h.snippet_hash)
+		       m.id AS member_id, h.wpm, h.accuracy, h.completed_at
+		FROM members m
+		JOIN practice_history h ON h.user_id = m.user_id
+		JOIN team_challenge_snippets s ON s.challenge_id = $2 AND s.snippet_hash = h.snippet_hash
+		JOIN team_challenges c ON c.id = $2
+		WHERE h.verification_status = 'verified'
+		  AND h.completed_at >= c.starts_at
+		  AND h.completed_at < c.ends_at
+		ORDER BY m.id, h.snippet_hash, h.wpm DESC, h.accuracy DESC, h.completed_at ASC
+	),
+	scores AS (
+		SELECT ROW_NUMBER() OVER (
+		           ORDER BY SUM(b.wpm) DESC, COUNT(*) DESC, AVG(b.accuracy) DESC, MAX(b.completed_at) ASC, m.ordinal
+		       )::int AS rank,
+		       m.id AS member_id,
+		       m.user_id,
+		       CASE WHEN m.display_name <> '' THEN m.display_name ELSE 'Member ' || m.ordinal END AS display_name,
+		       SUM(b.wpm)::int AS score,
+		       COUNT(*)::int AS snippets_completed,
+		       ROUND(AVG(b.accuracy)::numeric, 2)::float8 AS average_accuracy,
+		       MAX(b.completed_at) AS last_completed_at
+		FROM members m
+		JOIN best b ON b.member_id = m.id
+		GROUP BY m.id, m.user_id, m.display_name, m.ordinal
+	)`
+
+const challengeStandingColumns = `rank, member_id, user_id, display_name, score, snippets_completed, average_accuracy, last_completed_at`
+
+// Create schedules a challenge with its snippets.
+func (r *ChallengeRepository) Create(ctx context.Context, params CreateChallengeParams) (Challenge, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Challenge{}, fmt.Errorf("begin create challenge transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO team_challenges (team_id, name, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + challengeColumns + `;
+	`
+
+	challenge, err := scanChallenge(tx.QueryRowContext(ctx, query, params.TeamID, params.Name, params.StartsAt, params.EndsAt))
+	if err != nil {
+		return Challenge{}, fmt.Errorf("insert challenge: %w", err)
+	}
+
+	const snippetQuery = `
+		INSERT INTO team_challenge_snippets (challenge_id, snippet_id, snippet_hash, language)
+		VALUES ($1, $2, $3, $4);
+	`
+
+	for _, snippet := range params.Snippets {
+		if _, err := tx.ExecContext(ctx, snippetQuery, challenge.ID, nullString(snippet.SnippetID), snippet.SnippetHash, snippet.Language); err != nil {
+			return Challenge{}, fmt.Errorf("insert challenge snippet: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Challenge{}, fmt.Errorf("commit challenge: %w", err)
+	}
+
+	challenge.Snippets = params.Snippets
+	return challenge, nil
+}
+
+// ListByTeam returns the team's challenges, latest start first, without their snippets.
+func (r *ChallengeRepository) ListByTeam(ctx context.Context, teamID string) ([]Challenge, error) {
+	query := `
+		SELECT ` + challengeColumns + `
+		FROM team_challenges
+		WHERE team_id = $1
+		ORDER BY starts_at DESC, id;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("query challenges: %w", err)
+	}
+	defer rows.Close()
+
+	challenges := make([]Challenge, 0)
+	for rows.Next() {
+		challenge, err := scanChallenge(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan challenge: %w", err)
+		}
+
+		challenges = append(challenges, challenge)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate challenges: %w", err)
+	}
+
+	return challenges, nil
+}
+
+// Get returns a challenge of the team with its snippets.
+// Returns ErrNotFound if it does not exist or belongs to another team.
+func (r *ChallengeRepository) Get(ctx context.Context, teamID, challengeID string) (Challenge, error) {
+	query := `
+		SELECT ` + challengeColumns + `
+		FROM team_challenges
+		WHERE id = $1 AND team_id = $2;
+	`
+
+	challenge, err := scanChallenge(r.db.QueryRowContext(ctx, query, challengeID, teamID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Challenge{}, ErrNotFound
+	}
+	if err != nil {
+		return Challenge{}, fmt.Errorf("scan challenge: %w", err)
+	}
+
+	const snippetQuery = `
+		SELECT snippet_id, snippet_hash, language
+		FROM team_challenge_snippets
+		WHERE challenge_id = $1
+		ORDER BY language, snippet_hash;
+	`
+
+	rows, err := r.db.QueryContext(ctx, snippetQuery, challengeID)
+	if err != nil {
+		return Challenge{}, fmt.Errorf("query challenge snippets: %w", err)
+	}
+	defer rows.Close()
+
+	challenge.Snippets = make([]ChallengeSnippet, 0)
+	for rows.Next() {
+		var (
+			snippet   ChallengeSnippet
+			snippetID sql.NullString
+		)
+		if err := rows.Scan(&snippetID, &snippet.SnippetHash, &snippet.Language); err != nil {
+			return Challenge{}, fmt.Errorf("scan challenge snippet: %w", err)
+		}
+
+		snippet.SnippetID = snippetID.String
+		challenge.Snippets = append(challenge.Snippets, snippet)
+	}
+
+	if err := rows.Err(); err != nil {
+		return Challenge{}, fmt.Errorf("iterate challenge snippets: %w", err)
+	}
+
+	return challenge, nil
+}
+
+// Delete removes a challenge of the team. Returns ErrNotFound if it does not exist.
+func (r *ChallengeRepository) Delete(ctx context.Context, teamID, challengeID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM team_challenges WHERE id = $1 AND team_id = $2;`, challengeID, teamID)
+	if err != nil {
+		return fmt.Errorf("delete challenge: %w", err)
+	}
+
+	return requireAffected(result, "deleted challenge")
+}
+
+// Standings returns the ranking of a challenge. Closed challenges return the standings frozen
+// when they closed; others are ranked live from the runs recorded so far.
+func (r *ChallengeRepository) Standings(ctx context.Context, challenge Challenge) ([]ChallengeStanding, error) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+
+	if challenge.Status == ChallengeClosed {
+		query := `
+			SELECT ` + challengeStandingColumns + `
+			FROM team_challenge_standings
+			WHERE challenge_id = $1
+			ORDER BY rank;
+		`
+		rows, err = r.db.QueryContext(ctx, query, challenge.ID)
+	} else {
+		query := challengeScoresCTE + `
+			SELECT ` + challengeStandingColumns + `
+			FROM scores
+			ORDER BY rank;
+		`
+		rows, err = r.db.QueryContext(ctx, query, challenge.TeamID, challenge.ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query challenge standings: %w", err)
+	}
+	defer rows.Close()
+
+	standings := make([]ChallengeStanding, 0)
+	for rows.Next() {
+		var standing ChallengeStanding
+		if err := rows.Scan(
+			&standing.Rank,
+			&standing.MemberID,
+			&standing.UserID,
+			&standing.DisplayName,
+			&standing.Score,
+			&standing.SnippetsCompleted,
+			&standing.AverageAccuracy,
+			&standing.LastCompletedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan challenge standing: %w", err)
+		}
+
+		standings = append(standings, standing)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate challenge standings: %w", err)
+	}
+
+	return standings, nil
+}
+
+// RunSchedule opens challenges whose window has started and closes those whose window has ended,
+// writing their final standings in the same transaction.
+// Only one replica runs the schedule at a time; when another holds the lock, RunSchedule does nothing.
+// Closing is additionally guarded by the challenge status, so standings are computed exactly once.
+func (r *ChallengeRepository) RunSchedule(ctx context.Context) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1);`, challengeScheduleLock).Scan(&locked); err != nil {
+		return fmt.Errorf("acquire schedule lock: %w", err)
+	}
+
+	if !locked {
+		return nil
+	}
+
+	const openQuery = `
+		UPDATE team_challenges
+		SET status = 'open', opened_at = NOW()
+		WHERE status = 'scheduled' AND starts_at <= NOW() AND ends_at > NOW();
+	`
+
+	if _, err := tx.ExecContext(ctx, openQuery); err != nil {
+		return fmt.Errorf("open challenges: %w", err)
+	}
+
+	const dueQuery = `
+		SELECT id, team_id
+		FROM team_challenges
+		WHERE status <> 'closed' AND ends_at <= NOW()
+		ORDER BY ends_at, id
+		FOR UPDATE;
+	`
+
+	rows, err := tx.QueryContext(ctx, dueQuery)
+	if err != nil {
+		return fmt.Errorf("query due challenges: %w", err)
+	}
+
+	var due []Challenge
+	for rows.Next() {
+		var challenge Challenge
+		if err := rows.Scan(&challenge.ID, &challenge.TeamID); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan due challenge: %w", err)
+		}
+
+		due = append(due, challenge)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate due challenges: %w", err)
+	}
+
+	finalizeQuery := challengeScoresCTE + `
+		INSERT INTO team_challenge_standings (challenge_id, ` + challengeStandingColumns + `)
+		SELECT $2, ` + challengeStandingColumns + `
+		FROM scores
+		ON CONFLICT (challenge_id, user_id) DO NOTHING;
+	`
+
+	const closeQuery = `
+		UPDATE team_challenges
+		SET status = 'closed', closed_at = NOW()
+		WHERE id = $1 AND status <> 'closed';
+	`
+
+	for _, challenge := range due {
+		if _, err := tx.ExecContext(ctx, finalizeQuery, challenge.TeamID, challenge.ID); err != nil {
+			return fmt.Errorf("finalize challenge %s: %w", challenge.ID, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, closeQuery, challenge.ID); err != nil {
+			return fmt.Errorf("close challenge %s: %w", challenge.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit schedule: %w", err)
+	}
+
+	return nil
+}
+
+// deleteChallengeStandingsByUser removes the user from the final standings of every challenge.
+func deleteChallengeStandingsByUser(ctx context.Context, tx dbtx, userID string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM team_challenge_standings WHERE user_id = $1;`, userID); err != nil {
+		return fmt.Errorf("delete challenge standings: %w", err)
+	}
+
+	return nil
+}
+
+func scanChallenge(row rowScanner) (Challenge, error) {
+	var (
+		challenge Challenge
+		openedAt  sql.NullTime
+		closedAt  sql.NullTime
+	)
+
+	if err := row.Scan(
+		&challenge.ID,
+		&challenge.TeamID,
+		&challenge.Name,
+		&challenge.StartsAt,
+		&challenge.EndsAt,
+		&challenge.Status,
+		&challenge.CreatedAt,
+		&openedAt,
+		&closedAt,
+	); err != nil {
+		return Challenge{}, err
+	}
+
+	if openedAt.Valid {
+		challenge.OpenedAt = &openedAt.Time
+	}
+	if closedAt.Valid {
+		challenge.ClosedAt = &closedAt.Time
+	}
+
+	return challenge, nil
+}
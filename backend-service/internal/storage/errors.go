@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrNotFound is returned when the requested record does not exist (or is not visible to the caller).
+var ErrNotFound = errors.New("record not found")
+
+// ErrConflict is returned when a write would violate a uniqueness constraint.
+var ErrConflict = errors.New("record conflicts with an existing one")
+
+// uniqueViolation is the PostgreSQL SQLSTATE for unique_violation.
+const uniqueViolation = "23505"
+
+// isUniqueViolation reports whether err was caused by a unique constraint.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
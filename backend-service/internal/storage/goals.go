@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Goal kinds.
+const (
+	GoalDailyMinutes = "daily_minutes"
+	GoalWeeklyRuns   = "weekly_runs"
+	GoalTargetWPM    = "target_wpm"
+)
+
+// Goal is a practice target. An empty Language applies the goal to every language.
+type Goal struct {
+	ID       string
+	Kind     string
+	Language string
+	Target   int
+}
+
+// GoalSettings holds the time zone used to split practice into local days and weeks.
+type GoalSettings struct {
+	TimeZone string
+	Goals    []Goal
+}
+
+// GoalTotals is the practice counted toward goals in the current local day and week.
+type GoalTotals struct {
+	TodaySeconds int
+	WeekRuns     int
+	BestWPM      int
+}
+
+// PracticeStreak is a run of consecutive local days with at least one practice session.
+// Start and End are dates at midnight UTC.
+type PracticeStreak struct {
+	Start time.Time
+	End   time.Time
+	Days  int
+}
+
+// GoalRepository handles persistence of goals and reads the history they are measured against.
+type GoalRepository struct {
+	db *sql.DB
+}
+
+// NewGoalRepository creates a new GoalRepository.
+func NewGoalRepository(db *sql.DB) *GoalRepository {
+	return &GoalRepository{db: db}
+}
+
+// Get returns the user's goals and time zone. Users without settings get UTC and no goals.
+func (r *GoalRepository) Get(ctx context.Context, userID string) (GoalSettings, error) {
+	settings := GoalSettings{TimeZone: "UTC", Goals: make([]Goal, 0)}
+
+	err := r.db.QueryRowContext(ctx, `SELECT time_zone FROM user_goal_settings WHERE user_id = $1;`, userID).Scan(&settings.TimeZone)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return GoalSettings{}, fmt.Errorf("load goal settings: %w", err)
+	}
+
+	const query = `
+		SELECT id, kind, language, target
+		FROM user_goals
+		WHERE user_id = $1
+		ORDER BY kind, language;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return GoalSettings{}, fmt.Errorf("query goals: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var goal Goal
+		if err := rows.Scan(&goal.ID, &goal.Kind, &goal.Language, &goal.Target); err != nil {
+			return GoalSettings{}, fmt.Errorf("scan goal: %w", err)
+		}
+
+		settings.Goals = append(settings.Goals, goal)
+	}
+
+	if err := rows.Err(); err != nil {
+		return GoalSettings{}, fmt.Errorf("iterate goals: %w", err)
+	}
+
+	return settings, nil
+}
+
+// Replace stores the time zone and replaces the user's whole goal set in one transaction.
+func (r *GoalRepository) Replace(ctx context.Context, userID string, settings GoalSettings) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const upsertSettings = `
+		INSERT INTO user_goal_settings (user_id, time_zone)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET time_zone = EXCLUDED.time_zone, updated_at = NOW();
+	`
+
+	if _, err := tx.ExecContext(ctx, upsertSettings, userID, settings.TimeZone); err != nil {
+		return fmt.Errorf("save goal settings: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_goals WHERE user_id = $1;`, userID); err != nil {
+		return fmt.Errorf("delete goals: %w", err)
+	}
+
+	for _, goal := range settings.Goals {
+		const insertGoal = `
+			INSERT INTO user_goals (user_id, kind, language, target)
+			VALUES ($1, $2, $3, $4);
+		`
+
+		if _, err := tx.ExecContext(ctx, insertGoal, userID, goal.Kind, goal.Language, goal.Target); err != nil {
+			return fmt.Errorf("insert goal: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit goals: %w", err)
+	}
+
+	return nil
+}
+
+// Totals sums the user's practice since dayStart and weekStart, optionally restricted to a language,
+// together with the best WPM ever reached.
+func (r *GoalRepository) Totals(ctx context.Context, userID, language string, dayStart, weekStart time.Time) (GoalTotals, error) {
+	const query = `
+		SELECT COALESCE(SUM(duration_seconds) FILTER (WHERE completed_at >= $3), 0)::int,
+		       COUNT(*) FILTER (WHERE completed_at >= $4)::int,
+		       COALESCE(MAX(wpm), 0)
+		FROM practice_history
+		WHERE user_id = $1 AND ($2 = '' OR language = $2);
+	`
+
+	var totals GoalTotals
+	if err := r.db.QueryRowContext(ctx, query, userID, language, dayStart, weekStart).Scan(&totals.TodaySeconds, &totals.WeekRuns, &totals.BestWPM); err != nil {
+		return GoalTotals{}, fmt.Errorf("query goal totals: %w", err)
+	}
+
+	return totals, nil
+}
+
+// Streaks returns the user's practice streaks in the time zone, most recent first.
+// Consecutive local dates are grouped with the gaps-and-islands technique.
+func (r *GoalRepository) Streaks(ctx context.Context, userID, timeZone string) ([]PracticeStreak, error) {
+	const query = `
+		WITH days AS (
+			SELECT DISTINCT (completed_at AT TIME ZONE $2)::date AS day
+			FROM practice_history
+			WHERE user_id = $1
+		),
+		islands AS (
+			SELECT day, day - (ROW_NUMBER() OVER (ORDER BY day))::int AS island
+			FROM days
+		)
+		SELECT MIN(day), MAX(day), COUNT(*)::int
+		FROM islands
+		GROUP BY island
+		ORDER BY MAX(day) DESC;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("query streaks: %w", err)
+	}
+	defer rows.Close()
+
+	streaks := make([]PracticeStreak, 0)
+	for rows.Next() {
+		var streak PracticeStreak
+		if err := rows.Scan(&streak.Start, &streak.End, &streak.Days); err != nil {
+			return nil, fmt.Errorf("scan streak: %w", err)
+		}
+
+		streaks = append(streaks, streak)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate streaks: %w", err)
+	}
+
+	return streaks, nil
+}
+
+// deleteGoalsByUser removes the user's goals and goal settings.
+func deleteGoalsByUser(ctx context.Context, tx dbtx, userID string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_goals WHERE user_id = $1;`, userID); err != nil {
+		return fmt.Errorf("delete goals: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_goal_settings WHERE user_id = $1;`, userID); err != nil {
+		return fmt.Errorf("delete goal settings: %w", err)
+	}
+
+	return nil
+}
@@ -3,91 +3,258 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+
+	"code-type/backend/internal/typing"
 )
 
 // HistoryEntry represents a persisted practice session result.
+// SnippetID and SnippetHash are empty when the run was not linked to a snippet.
+// RaceID is set for runs finished in a multiplayer race.
+// VerificationStatus is one of the typing.Status values.
 type HistoryEntry struct {
-	ID              string
-	UserID          string
-	Language        string
-	WPM             int
-	Accuracy        int
-	Errors          int
-	DurationSeconds int
-	CompletedAt     time.Time
-	CreatedAt       time.Time
+	ID                 string
+	UserID             string
+	Language           string
+	SnippetID          string
+	SnippetHash        string
+	RaceID             string
+	WPM                int
+	Accuracy           int
+	Errors             int
+	DurationSeconds    int
+	CompletedAt        time.Time
+	CreatedAt          time.Time
+	VerificationStatus string
 }
 
 // CreateHistoryParams contains parameters to insert a new history entry.
+// Keystrokes is optional; when present the log is stored compressed in the same transaction,
+// together with the KeyStats and BigramStats derived from it.
+// VerificationStatus defaults to typing.StatusUnverified when empty.
+// ClientID is the optional client-generated UUID of a run synced from an offline queue.
 type CreateHistoryParams struct {
-	UserID          string
-	Language        string
-	WPM             int
-	Accuracy        int
-	Errors          int
-	DurationSeconds int
-	CompletedAt     time.Time
+	UserID             string
+	ClientID           string
+	Language           string
+	SnippetID          string
+	SnippetHash        string
+	RaceID             string
+	WPM                int
+	Accuracy           int
+	Errors             int
+	DurationSeconds    int
+	CompletedAt        time.Time
+	Keystrokes         []typing.Keystroke
+	KeyStats           []typing.KeyStat
+	BigramStats        []typing.BigramStat
+	VerificationStatus typing.Status
+}
+
+// HistoryFilter narrows history queries. Zero values and nil pointers are ignored.
+// From is inclusive and To is exclusive, both applied to completed_at.
+type HistoryFilter struct {
+	SnippetID   string
+	SnippetHash string
+	Language    string
+	From        *time.Time
+	To          *time.Time
+	MinWPM      *int
+	MaxWPM      *int
+	MinAccuracy *int
+	MaxAccuracy *int
+}
+
+// HistorySort selects the ordering of a history listing.
+// Field must satisfy IsHistorySortField; an empty Field sorts by completed_at.
+type HistorySort struct {
+	Field     string
+	Ascending bool
+}
+
+// historySortColumns maps public sort fields to trusted column names.
+var historySortColumns = map[string]string{
+	"completed_at": "completed_at",
+	"wpm":          "wpm",
+	"accuracy":     "accuracy",
+	"errors":       "errors",
+}
+
+// IsHistorySortField reports whether field can be used in HistorySort.
+func IsHistorySortField(field string) bool {
+	_, ok := historySortColumns[field]
+	return ok
+}
+
+// HistoryCursor identifies the last entry of a page for keyset pagination.
+// Value holds that entry's sort column when sorting by a numeric field.
+type HistoryCursor struct {
+	Value       int
+	CompletedAt time.Time
+	ID          string
+}
+
+// ListHistoryParams selects a page of a user's history.
+// When After is set the page starts strictly after that entry and Offset is ignored.
+type ListHistoryParams struct {
+	UserID string
+	Filter HistoryFilter
+	Sort   HistorySort
+	Limit  int
+	Offset int
+	After  *HistoryCursor
 }
 
 // HistoryRepository handles persistence of practice history entries.
 type HistoryRepository struct {
-	db *sql.DB
+	db          *sql.DB
+	createHooks []CreateHook
 }
 
+// CreateHook is called with every history entry committed by Create.
+// Hooks run on the request path, so they must hand slow work off instead of doing it inline.
+type CreateHook func(entry HistoryEntry)
+
 // NewHistoryRepository creates a new HistoryRepository.
 func NewHistoryRepository(db *sql.DB) *HistoryRepository {
 	return &HistoryRepository{db: db}
 }
 
-// Create inserts a new history entry and returns the stored record.
+// OnCreate registers a hook that runs after each successful Create.
+// Hooks must be registered before the repository starts serving requests.
+func (r *HistoryRepository) OnCreate(hook CreateHook) {
+	r.createHooks = append(r.createHooks, hook)
+}
+
+const historyColumns = `id, user_id, language, snippet_id, snippet_hash, race_id, wpm, accuracy, errors, duration_seconds, completed_at, created_at, verification_status`
+
+// Create inserts a new history entry (and its keystroke log, if any) and returns the stored record.
 func (r *HistoryRepository) Create(ctx context.Context, params CreateHistoryParams) (HistoryEntry, error) {
-	const query = `
-		INSERT INTO practice_history (user_id, language, wpm, accuracy, errors, duration_seconds, completed_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, user_id, language, wpm, accuracy, errors, duration_seconds, completed_at, created_at;
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return HistoryEntry{}, fmt.Errorf("begin create history transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	entry, err := insertHistoryEntry(ctx, tx, params)
+	if err != nil {
+		return HistoryEntry{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return HistoryEntry{}, fmt.Errorf("commit history entry: %w", err)
+	}
+
+	for _, hook := range r.createHooks {
+		hook(entry)
+	}
+
+	return entry, nil
+}
+
+// insertHistoryEntry writes the history row and its keystroke log using the given transaction.
+// Returns ErrConflict if the user already has a run with params.ClientID.
+func insertHistoryEntry(ctx context.Context, tx dbtx, params CreateHistoryParams) (HistoryEntry, error) {
+	query := `
+		INSERT INTO practice_history (user_id, language, snippet_id, snippet_hash, race_id, wpm, accuracy, errors, duration_seconds, completed_at, verification_status, client_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (user_id, client_id) WHERE client_id IS NOT NULL DO NOTHING
+		RETURNING ` + historyColumns + `;
 	`
 
-	row := r.db.QueryRowContext(ctx, query,
+	status := params.VerificationStatus
+	if status == "" {
+		status = typing.StatusUnverified
+	}
+
+	row := tx.QueryRowContext(ctx, query,
 		params.UserID,
 		params.Language,
+		nullString(params.SnippetID),
+		nullString(params.SnippetHash),
+		nullString(params.RaceID),
 		params.WPM,
 		params.Accuracy,
 		params.Errors,
 		params.DurationSeconds,
 		params.CompletedAt,
+		string(status),
+		nullString(params.ClientID),
 	)
 
-	var entry HistoryEntry
-	if err := row.Scan(
-		&entry.ID,
-		&entry.UserID,
-		&entry.Language,
-		&entry.WPM,
-		&entry.Accuracy,
-		&entry.Errors,
-		&entry.DurationSeconds,
-		&entry.CompletedAt,
-		&entry.CreatedAt,
-	); err != nil {
+	entry, err := scanHistoryEntry(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return HistoryEntry{}, ErrConflict
+	}
+	if err != nil {
 		return HistoryEntry{}, fmt.Errorf("scan inserted history entry: %w", err)
 	}
 
+	if len(params.Keystrokes) > 0 {
+		if err := insertKeystrokes(ctx, tx, entry, params.Keystrokes); err != nil {
+			return HistoryEntry{}, err
+		}
+
+		if err := insertKeystrokeStats(ctx, tx, entry, params.KeyStats, params.BigramStats); err != nil {
+			return HistoryEntry{}, err
+		}
+	}
+
 	return entry, nil
 }
 
-// ListByUser returns practice history entries for the specified user ordered by completion date desc.
-func (r *HistoryRepository) ListByUser(ctx context.Context, userID string, limit, offset int) ([]HistoryEntry, error) {
-	const query = `
-		SELECT id, user_id, language, wpm, accuracy, errors, duration_seconds, completed_at, created_at
+// ListByUser returns practice history entries for the specified user matching the filter.
+// Rows are ordered by the sort field, then completed_at and id in the same direction, so pages are
+// stable and keyset cursors work for every sort. The default order (completed_at DESC, id DESC)
+// matches idx_practice_history_user_completed_at.
+func (r *HistoryRepository) ListByUser(ctx context.Context, params ListHistoryParams) ([]HistoryEntry, error) {
+	conditions, args := historyFilterConditions(params.UserID, params.Filter)
+
+	column, ok := historySortColumns[params.Sort.Field]
+	if !ok {
+		column = "completed_at"
+	}
+
+	comparison := "<"
+	if params.Sort.Ascending {
+		comparison = ">"
+	}
+
+	orderBy := historyOrder(params.Sort, "")
+	keyset := "(completed_at, id)"
+	if column != "completed_at" {
+		keyset = fmt.Sprintf("(%s, completed_at, id)", column)
+	}
+
+	offset := params.Offset
+	if params.After != nil {
+		placeholders := make([]string, 0, 3)
+		if column != "completed_at" {
+			args = append(args, params.After.Value)
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+
+		args = append(args, params.After.CompletedAt, params.After.ID)
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)-1), fmt.Sprintf("$%d", len(args)))
+
+		conditions = append(conditions, fmt.Sprintf("%s %s (%s)", keyset, comparison, strings.Join(placeholders, ", ")))
+		offset = 0
+	}
+
+	args = append(args, params.Limit, offset)
+
+	query := fmt.Sprintf(`
+		SELECT %s
 		FROM practice_history
-		WHERE user_id = $1
-		ORDER BY completed_at DESC
-		LIMIT $2 OFFSET $3;
-	`
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d OFFSET $%d;
+	`, historyColumns, strings.Join(conditions, " AND "), orderBy, len(args)-1, len(args))
 
-	rows, err := r.db.QueryContext(ctx, query, userID, limit, offset)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query history entries: %w", err)
 	}
@@ -95,18 +262,8 @@ func (r *HistoryRepository) ListByUser(ctx context.Context, userID string, limit
 
 	entries := make([]HistoryEntry, 0)
 	for rows.Next() {
-		var entry HistoryEntry
-		if err := rows.Scan(
-			&entry.ID,
-			&entry.UserID,
-			&entry.Language,
-			&entry.WPM,
-			&entry.Accuracy,
-			&entry.Errors,
-			&entry.DurationSeconds,
-			&entry.CompletedAt,
-			&entry.CreatedAt,
-		); err != nil {
+		entry, err := scanHistoryEntry(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan history entry: %w", err)
 		}
 
@@ -120,16 +277,196 @@ func (r *HistoryRepository) ListByUser(ctx context.Context, userID string, limit
 	return entries, nil
 }
 
+// BestBySnippet returns the user's personal best run on a snippet, identified by catalog ID or content hash.
+// Runs are ranked by WPM, then accuracy, then the earliest completion.
+// Returns ErrNotFound if the user has no runs on that snippet.
+func (r *HistoryRepository) BestBySnippet(ctx context.Context, userID, snippetID, snippetHash string) (HistoryEntry, error) {
+	conditions, args := historyFilterConditions(userID, HistoryFilter{SnippetID: snippetID, SnippetHash: snippetHash})
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM practice_history
+		WHERE %s
+		ORDER BY wpm DESC, accuracy DESC, completed_at ASC
+		LIMIT 1;
+	`, historyColumns, strings.Join(conditions, " AND "))
+
+	entry, err := scanHistoryEntry(r.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return HistoryEntry{}, ErrNotFound
+	}
+	if err != nil {
+		return HistoryEntry{}, fmt.Errorf("scan best history entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+// GetByID returns a single history entry owned by the user.
+// Returns ErrNotFound if the entry does not exist or belongs to another user.
+func (r *HistoryRepository) GetByID(ctx context.Context, userID, id string) (HistoryEntry, error) {
+	query := `
+		SELECT ` + historyColumns + `
+		FROM practice_history
+		WHERE id = $1 AND user_id = $2;
+	`
+
+	entry, err := scanHistoryEntry(r.db.QueryRowContext(ctx, query, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return HistoryEntry{}, ErrNotFound
+	}
+	if err != nil {
+		return HistoryEntry{}, fmt.Errorf("scan history entry: %w", err)
+	}
+
+	return entry, nil
+}
+
+// DeleteByID removes a single history entry owned by the user.
+// Returns ErrNotFound if the entry does not exist or belongs to another user.
+func (r *HistoryRepository) DeleteByID(ctx context.Context, userID, id string) error {
+	const query = `
+		DELETE FROM practice_history
+		WHERE id = $1 AND user_id = $2;
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("delete history entry: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("read deleted history entry count: %w", err)
+	}
+
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
 // DeleteByUser removes all history entries for the specified user.
 func (r *HistoryRepository) DeleteByUser(ctx context.Context, userID string) error {
+	return deleteHistoryByUser(ctx, r.db, userID)
+}
+
+// deleteHistoryByUser removes the user's history. Keystroke logs, keystroke statistics
+// and idempotency keys go with it through their foreign keys.
+func deleteHistoryByUser(ctx context.Context, tx dbtx, userID string) error {
 	const query = `
 		DELETE FROM practice_history
 		WHERE user_id = $1;
 	`
 
-	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
 		return fmt.Errorf("delete history entries: %w", err)
 	}
 
 	return nil
 }
+
+// historyOrder returns the ORDER BY list for sort with columns qualified by prefix.
+// Rows are ordered by the sort field, then completed_at and id in the same direction.
+func historyOrder(sort HistorySort, prefix string) string {
+	direction := "DESC"
+	if sort.Ascending {
+		direction = "ASC"
+	}
+
+	column, ok := historySortColumns[sort.Field]
+	if !ok || column == "completed_at" {
+		return fmt.Sprintf("%[1]scompleted_at %[2]s, %[1]sid %[2]s", prefix, direction)
+	}
+
+	return fmt.Sprintf("%[1]s%[2]s %[3]s, %[1]scompleted_at %[3]s, %[1]sid %[3]s", prefix, column, direction)
+}
+
+// historyFilterConditions scopes a query to the user and the optional filter fields.
+// Every value is passed as a positional parameter.
+func historyFilterConditions(userID string, filter HistoryFilter) ([]string, []any) {
+	conditions := []string{"user_id = $1"}
+	args := []any{userID}
+
+	add := func(condition string, value any) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.SnippetID != "" {
+		add("snippet_id = $%d", filter.SnippetID)
+	}
+
+	if filter.SnippetHash != "" {
+		add("snippet_hash = $%d", filter.SnippetHash)
+	}
+
+	if filter.Language != "" {
+		add("language = $%d", filter.Language)
+	}
+
+	if filter.From != nil {
+		add("completed_at >= $%d", *filter.From)
+	}
+
+	if filter.To != nil {
+		add("completed_at < $%d", *filter.To)
+	}
+
+	if filter.MinWPM != nil {
+		add("wpm >= $%d", *filter.MinWPM)
+	}
+
+	if filter.MaxWPM != nil {
+		add("wpm <= $%d", *filter.MaxWPM)
+	}
+
+	if filter.MinAccuracy != nil {
+		add("accuracy >= $%d", *filter.MinAccuracy)
+	}
+
+	if filter.MaxAccuracy != nil {
+		add("accuracy <= $%d", *filter.MaxAccuracy)
+	}
+
+	return conditions, args
+}
+
+func scanHistoryEntry(row rowScanner) (HistoryEntry, error) {
+	var (
+		entry       HistoryEntry
+		snippetID   sql.NullString
+		snippetHash sql.NullString
+		raceID      sql.NullString
+	)
+
+	if err := row.Scan(
+		&entry.ID,
+		&entry.UserID,
+		&entry.Language,
+		&snippetID,
+		&snippetHash,
+		&raceID,
+		&entry.WPM,
+		&entry.Accuracy,
+		&entry.Errors,
+		&entry.DurationSeconds,
+		&entry.CompletedAt,
+		&entry.CreatedAt,
+		&entry.VerificationStatus,
+	); err != nil {
+		return HistoryEntry{}, err
+	}
+
+	entry.SnippetID = snippetID.String
+	entry.SnippetHash = snippetHash.String
+	entry.RaceID = raceID.String
+
+	return entry, nil
+}
+
+// nullString maps an empty string to SQL NULL.
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
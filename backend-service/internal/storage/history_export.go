@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"code-type/backend/internal/typing"
+)
+
+// HistoryScan selects the entries streamed by ForEach.
+// When WithKeystrokes is set each entry comes with its decoded keystroke log.
+type HistoryScan struct {
+	UserID         string
+	Filter         HistoryFilter
+	Sort           HistorySort
+	WithKeystrokes bool
+}
+
+// HistoryVisitor receives one entry of a history scan. Keystrokes is nil when the run has no log
+// or the scan was not asked for logs. Returning an error stops the scan.
+type HistoryVisitor func(entry HistoryEntry, keystrokes []typing.Keystroke) error
+
+// ForEach streams the user's history entries matching the scan to visit in the same order
+// ListByUser would return them, reading rows from the cursor one at a time instead of loading
+// the whole history into memory. The database connection is held until the scan ends,
+// so visit should not block for long.
+func (r *HistoryRepository) ForEach(ctx context.Context, scan HistoryScan, visit HistoryVisitor) error {
+	conditions, args := historyFilterConditions(scan.UserID, scan.Filter)
+
+	keystrokeColumns, keystrokeJoin := "NULL::text, NULL::bytea", ""
+	if scan.WithKeystrokes {
+		keystrokeColumns, keystrokeJoin = "k.encoding, k.data", "LEFT JOIN practice_keystrokes k ON k.history_id = h.id"
+	}
+
+	// Filter conditions use bare column names, so they are applied before joining the logs.
+	query := fmt.Sprintf(`
+		SELECT %s, %s
+		FROM (
+			SELECT %s
+			FROM practice_history
+			WHERE %s
+		) h
+		%s
+		ORDER BY %s;
+	`, prefixedHistoryColumns, keystrokeColumns, historyColumns, strings.Join(conditions, " AND "), keystrokeJoin, historyOrder(scan.Sort, "h."))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("query history entries: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			encoding sql.NullString
+			data     []byte
+		)
+
+		entry, err := scanHistoryEntry(extendedRow{row: rows, extra: []any{&encoding, &data}})
+		if err != nil {
+			return fmt.Errorf("scan history entry: %w", err)
+		}
+
+		var keystrokes []typing.Keystroke
+		if encoding.Valid {
+			if keystrokes, err = decodeKeystrokes(encoding.String, data); err != nil {
+				return fmt.Errorf("decode keystrokes of %s: %w", entry.ID, err)
+			}
+		}
+
+		if err := visit(entry, keystrokes); err != nil {
+			return err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate history entries: %w", err)
+	}
+
+	return nil
+}
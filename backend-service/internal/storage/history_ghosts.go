@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"code-type/backend/internal/typing"
+)
+
+// Ghost is a recorded run someone can race against.
+// DisplayName is the owner's profile name, empty when the owner has no profile.
+type Ghost struct {
+	Entry       HistoryEntry
+	DisplayName string
+	Keystrokes  []typing.Keystroke
+}
+
+// prefixedHistoryColumns qualifies historyColumns for queries that join other tables.
+var prefixedHistoryColumns = "h." + strings.ReplaceAll(historyColumns, ", ", ", h.")
+
+// Ghost returns a run with its keystroke log if the viewer may race it: the viewer's own runs,
+// and verified runs of users who opted in to leaderboards.
+// Returns ErrNotFound if the run does not exist, is not visible or has no keystroke log.
+func (r *HistoryRepository) Ghost(ctx context.Context, viewerID, historyID string) (Ghost, error) {
+	query := `
+		SELECT ` + prefixedHistoryColumns + `, COALESCE(p.display_name, ''), k.encoding, k.data
+		FROM practice_history h
+		JOIN practice_keystrokes k ON k.history_id = h.id
+		LEFT JOIN user_profiles p ON p.user_id = h.user_id
+		WHERE h.id = $1
+		  AND (h.user_id = $2 OR (h.verification_status = 'verified' AND p.leaderboard_opt_in));
+	`
+
+	var (
+		ghost    Ghost
+		encoding string
+		data     []byte
+		err      error
+	)
+
+	row := r.db.QueryRowContext(ctx, query, historyID, viewerID)
+	ghost.Entry, err = scanHistoryEntry(extendedRow{row: row, extra: []any{&ghost.DisplayName, &encoding, &data}})
+	if errors.Is(err, sql.ErrNoRows) {
+		return Ghost{}, ErrNotFound
+	}
+	if err != nil {
+		return Ghost{}, fmt.Errorf("scan ghost: %w", err)
+	}
+
+	if ghost.Keystrokes, err = decodeKeystrokes(encoding, data); err != nil {
+		return Ghost{}, err
+	}
+
+	return ghost, nil
+}
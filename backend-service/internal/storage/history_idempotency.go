@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrIdempotencyMismatch is returned when an idempotency key is reused for a different request.
+var ErrIdempotencyMismatch = errors.New("idempotency key was used for a different request")
+
+// IdempotencyKey identifies a client request that may be retried.
+// RequestHash fingerprints the request body so a replay can be told apart from a reused key.
+type IdempotencyKey struct {
+	Key         string
+	RequestHash string
+}
+
+// Replay returns the entry recorded for the user's idempotency key.
+// Returns ErrNotFound if the key has not been used, and ErrIdempotencyMismatch
+// if it was used with a different request hash.
+func (r *HistoryRepository) Replay(ctx context.Context, userID string, key IdempotencyKey) (HistoryEntry, error) {
+	query := `
+		SELECT ` + prefixedHistoryColumns + `, k.request_hash
+		FROM history_idempotency_keys k
+		JOIN practice_history h ON h.id = k.history_id
+		WHERE k.user_id = $1 AND k.idempotency_key = $2;
+	`
+
+	var requestHash string
+	row := r.db.QueryRowContext(ctx, query, userID, key.Key)
+	entry, err := scanHistoryEntry(extendedRow{row: row, extra: []any{&requestHash}})
+	if errors.Is(err, sql.ErrNoRows) {
+		return HistoryEntry{}, ErrNotFound
+	}
+	if err != nil {
+		return HistoryEntry{}, fmt.Errorf("scan idempotent history entry: %w", err)
+	}
+
+	if requestHash != key.RequestHash {
+		return HistoryEntry{}, ErrIdempotencyMismatch
+	}
+
+	return entry, nil
+}
+
+// CreateIdempotent inserts a history entry like Create and records key for it in the same transaction.
+// If a concurrent request committed the same key first, the entry it stored is returned instead
+// with true; that entry is subject to the same ErrIdempotencyMismatch check as Replay.
+func (r *HistoryRepository) CreateIdempotent(ctx context.Context, params CreateHistoryParams, key IdempotencyKey) (HistoryEntry, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return HistoryEntry{}, false, fmt.Errorf("begin create history transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	entry, err := insertHistoryEntry(ctx, tx, params)
+	if err != nil {
+		return HistoryEntry{}, false, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO history_idempotency_keys (user_id, idempotency_key, request_hash, history_id)
+		VALUES ($1, $2, $3, $4);
+	`, params.UserID, key.Key, key.RequestHash, entry.ID)
+	if isUniqueViolation(err) {
+		if err := tx.Rollback(); err != nil {
+			return HistoryEntry{}, false, fmt.Errorf("roll back duplicate history entry: %w", err)
+		}
+
+		entry, err = r.Replay(ctx, params.UserID, key)
+		if err != nil {
+			return HistoryEntry{}, false, err
+		}
+		return entry, true, nil
+	}
+	if err != nil {
+		return HistoryEntry{}, false, fmt.Errorf("insert idempotency key: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return HistoryEntry{}, false, fmt.Errorf("commit history entry: %w", err)
+	}
+
+	for _, hook := range r.createHooks {
+		hook(entry)
+	}
+
+	return entry, false, nil
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"code-type/backend/internal/typing"
+)
+
+// historyImportLock is the first key of the per-user advisory lock that serializes imports,
+// so two concurrent uploads of the same file cannot both miss each other's rows.
+const historyImportLock = 7_231_003
+
+// importBatchSize is the number of rows sent per COPY into the staging table.
+const importBatchSize = 1000
+
+var historyImportColumns = []string{
+	"ordinal", "language", "snippet_id", "snippet_hash", "wpm", "accuracy", "errors",
+	"duration_seconds", "completed_at", "verification_status",
+}
+
+// ImportHistoryResult reports the outcome of Import. Duplicates holds the indexes of the
+// given rows that were skipped because the user already had a run with the same
+// completed_at and wpm, or because an earlier row of the same import had them.
+type ImportHistoryResult struct {
+	Imported   int
+	Duplicates []int
+}
+
+// Import inserts many runs for a user in one transaction. Rows are copied into a staging table
+// in batches, duplicates of existing runs (same completed_at second and wpm) are dropped there,
+// and the remainder is inserted with a single statement. Keystroke logs are not imported
+// and create hooks are not called.
+func (r *HistoryRepository) Import(ctx context.Context, userID string, rows []CreateHistoryParams) (ImportHistoryResult, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return ImportHistoryResult{}, fmt.Errorf("acquire import connection: %w", err)
+	}
+	defer conn.Close()
+
+	var result ImportHistoryResult
+	err = conn.Raw(func(driverConn any) error {
+		pgxConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.New("import requires a pgx connection")
+		}
+
+		result, err = importHistory(ctx, pgxConn.Conn(), userID, rows)
+		return err
+	})
+	if err != nil {
+		return ImportHistoryResult{}, err
+	}
+
+	return result, nil
+}
+
+func importHistory(ctx context.Context, conn *pgx.Conn, userID string, rows []CreateHistoryParams) (ImportHistoryResult, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return ImportHistoryResult{}, fmt.Errorf("begin import transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1, hashtext($2));`, historyImportLock, userID); err != nil {
+		return ImportHistoryResult{}, fmt.Errorf("lock history import: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		CREATE TEMP TABLE history_import (
+			ordinal INTEGER PRIMARY KEY,
+			language TEXT NOT NULL,
+			snippet_id TEXT,
+			snippet_hash TEXT,
+			wpm INTEGER NOT NULL,
+			accuracy INTEGER NOT NULL,
+			errors INTEGER NOT NULL,
+			duration_seconds INTEGER NOT NULL,
+			completed_at TIMESTAMPTZ NOT NULL,
+			verification_status TEXT NOT NULL
+		) ON COMMIT DROP;
+	`); err != nil {
+		return ImportHistoryResult{}, fmt.Errorf("create import staging table: %w", err)
+	}
+
+	for start := 0; start < len(rows); start += importBatchSize {
+		end := min(start+importBatchSize, len(rows))
+
+		source := pgx.CopyFromSlice(end-start, func(i int) ([]any, error) {
+			params := rows[start+i]
+
+			status := params.VerificationStatus
+			if status == "" {
+				status = typing.StatusUnverified
+			}
+
+			return []any{
+				start + i,
+				params.Language,
+				nullString(params.SnippetID),
+				nullString(params.SnippetHash),
+				params.WPM,
+				params.Accuracy,
+				params.Errors,
+				params.DurationSeconds,
+				params.CompletedAt,
+				string(status),
+			}, nil
+		})
+
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"history_import"}, historyImportColumns, source); err != nil {
+			return ImportHistoryResult{}, fmt.Errorf("copy import batch: %w", err)
+		}
+	}
+
+	// completed_at is compared to the second because exports (ours and other sites') drop
+	// fractions the original run may have had. Both EXISTS clauses see the staging table as it
+	// was before the delete, so of several identical rows in one file only the first survives.
+	duplicateRows, err := tx.Query(ctx, `
+		DELETE FROM history_import s
+		WHERE EXISTS (
+			SELECT 1 FROM practice_history h
+			WHERE h.user_id = $1
+				AND h.completed_at >= date_trunc('second', s.completed_at)
+				AND h.completed_at < date_trunc('second', s.completed_at) + INTERVAL '1 second'
+				AND h.wpm = s.wpm
+		) OR EXISTS (
+			SELECT 1 FROM history_import t
+			WHERE date_trunc('second', t.completed_at) = date_trunc('second', s.completed_at)
+				AND t.wpm = s.wpm
+				AND t.ordinal < s.ordinal
+		)
+		RETURNING s.ordinal;
+	`, userID)
+	if err != nil {
+		return ImportHistoryResult{}, fmt.Errorf("remove duplicate imports: %w", err)
+	}
+
+	var result ImportHistoryResult
+	result.Duplicates, err = pgx.CollectRows(duplicateRows, pgx.RowTo[int])
+	if err != nil {
+		return ImportHistoryResult{}, fmt.Errorf("read duplicate imports: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, `
+		INSERT INTO practice_history (user_id, language, snippet_id, snippet_hash, wpm, accuracy, errors, duration_seconds, completed_at, verification_status)
+		SELECT $1, language, snippet_id::uuid, snippet_hash, wpm, accuracy, errors, duration_seconds, completed_at, verification_status
+		FROM history_import
+		ORDER BY ordinal;
+	`, userID)
+	if err != nil {
+		return ImportHistoryResult{}, fmt.Errorf("insert imported history: %w", err)
+	}
+	result.Imported = int(tag.RowsAffected())
+
+	if err := tx.Commit(ctx); err != nil {
+		return ImportHistoryResult{}, fmt.Errorf("commit history import: %w", err)
+	}
+
+	return result, nil
+}
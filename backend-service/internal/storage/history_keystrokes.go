@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"code-type/backend/internal/typing"
+)
+
+// insertKeystrokes stores the compressed keystroke log for a history entry.
+func insertKeystrokes(ctx context.Context, tx dbtx, entry HistoryEntry, keystrokes []typing.Keystroke) error {
+	const query = `
+		INSERT INTO practice_keystrokes (history_id, user_id, encoding, keystroke_count, data)
+		VALUES ($1, $2, $3, $4, $5);
+	`
+
+	data, err := typing.Compress(keystrokes)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, query, entry.ID, entry.UserID, typing.Encoding, len(keystrokes), data); err != nil {
+		return fmt.Errorf("insert keystrokes: %w", err)
+	}
+
+	return nil
+}
+
+// insertKeystrokeStats stores the per-key and per-bigram aggregates of a run.
+// Each set is written with a single statement by unnesting parallel arrays.
+func insertKeystrokeStats(ctx context.Context, tx dbtx, entry HistoryEntry, keys []typing.KeyStat, bigrams []typing.BigramStat) error {
+	if len(keys) > 0 {
+		const query = `
+			INSERT INTO history_key_stats (history_id, user_id, language, key, presses, errors, latency_ms_total, latency_samples)
+			SELECT $1, $2, $3, k.key, k.presses, k.errors, k.latency, k.samples
+			FROM unnest($4::text[], $5::int[], $6::int[], $7::bigint[], $8::int[]) AS k(key, presses, errors, latency, samples);
+		`
+
+		names := make([]string, len(keys))
+		presses := make([]int64, len(keys))
+		errorCounts := make([]int64, len(keys))
+		latencies := make([]int64, len(keys))
+		samples := make([]int64, len(keys))
+		for i, k := range keys {
+			names[i] = k.Key
+			presses[i] = int64(k.Presses)
+			errorCounts[i] = int64(k.Errors)
+			latencies[i] = int64(k.LatencyMillis)
+			samples[i] = int64(k.LatencySamples)
+		}
+
+		if _, err := tx.ExecContext(ctx, query, entry.ID, entry.UserID, entry.Language,
+			names, presses, errorCounts, latencies, samples); err != nil {
+			return fmt.Errorf("insert key stats: %w", err)
+		}
+	}
+
+	if len(bigrams) > 0 {
+		const query = `
+			INSERT INTO history_bigram_stats (history_id, user_id, language, bigram, occurrences, latency_ms_total)
+			SELECT $1, $2, $3, b.bigram, b.occurrences, b.latency
+			FROM unnest($4::text[], $5::int[], $6::bigint[]) AS b(bigram, occurrences, latency);
+		`
+
+		names := make([]string, len(bigrams))
+		occurrences := make([]int64, len(bigrams))
+		latencies := make([]int64, len(bigrams))
+		for i, b := range bigrams {
+			names[i] = b.Bigram
+			occurrences[i] = int64(b.Occurrences)
+			latencies[i] = int64(b.LatencyMillis)
+		}
+
+		if _, err := tx.ExecContext(ctx, query, entry.ID, entry.UserID, entry.Language,
+			names, occurrences, latencies); err != nil {
+			return fmt.Errorf("insert bigram stats: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Keystrokes returns the keystroke log recorded with a history entry owned by the user.
+// Returns ErrNotFound if the entry does not exist, belongs to another user or has no log.
+func (r *HistoryRepository) Keystrokes(ctx context.Context, userID, historyID string) ([]typing.Keystroke, error) {
+	const query = `
+		SELECT encoding, data
+		FROM practice_keystrokes
+		WHERE history_id = $1 AND user_id = $2;
+	`
+
+	var (
+		encoding string
+		data     []byte
+	)
+
+	err := r.db.QueryRowContext(ctx, query, historyID, userID).Scan(&encoding, &data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scan keystrokes: %w", err)
+	}
+
+	return decodeKeystrokes(encoding, data)
+}
+
+func decodeKeystrokes(encoding string, data []byte) ([]typing.Keystroke, error) {
+	if encoding != typing.Encoding {
+		return nil, fmt.Errorf("unsupported keystroke encoding %q", encoding)
+	}
+
+	return typing.Decompress(data)
+}
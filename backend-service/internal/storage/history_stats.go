@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// HistoryStats holds aggregate numbers over a set of practice runs.
+type HistoryStats struct {
+	Runs            int
+	TotalSeconds    int
+	TotalErrors     int
+	AverageWPM      float64
+	AverageAccuracy float64
+	BestWPM         int
+	BestAccuracy    int
+}
+
+// LanguageStats holds aggregates for a single language.
+type LanguageStats struct {
+	Language string
+	HistoryStats
+}
+
+// PeriodStats holds aggregates for a single time bucket starting at Start.
+type PeriodStats struct {
+	Start time.Time
+	HistoryStats
+}
+
+// HistoryAggregate is the full statistics breakdown for a user.
+type HistoryAggregate struct {
+	Totals    HistoryStats
+	Languages []LanguageStats
+	Periods   []PeriodStats
+}
+
+// AggregateParams controls the time-bucketed part of the aggregate.
+// Bucket must be one of "day", "week" or "month"; TimeZone is an IANA zone name
+// used to decide bucket boundaries; Periods is the number of most recent buckets to return.
+type AggregateParams struct {
+	UserID   string
+	Bucket   string
+	TimeZone string
+	Periods  int
+}
+
+const statsColumns = `
+	COUNT(*),
+	COALESCE(SUM(duration_seconds), 0),
+	COALESCE(SUM(errors), 0),
+	COALESCE(ROUND(AVG(wpm)::numeric, 2), 0)::float8,
+	COALESCE(ROUND(AVG(accuracy)::numeric, 2), 0)::float8,
+	COALESCE(MAX(wpm), 0),
+	COALESCE(MAX(accuracy), 0)`
+
+// Aggregate computes lifetime totals, per-language and per-period statistics in SQL,
+// so results cover the user's whole history regardless of its size.
+func (r *HistoryRepository) Aggregate(ctx context.Context, params AggregateParams) (HistoryAggregate, error) {
+	aggregate := HistoryAggregate{
+		Languages: make([]LanguageStats, 0),
+		Periods:   make([]PeriodStats, 0),
+	}
+
+	// GROUPING SETS yields the lifetime totals (language IS NULL) and one row per language in a single scan.
+	languageQuery := `
+		SELECT language, GROUPING(language) = 1, ` + statsColumns + `
+		FROM practice_history
+		WHERE user_id = $1
+		GROUP BY GROUPING SETS ((), (language))
+		ORDER BY language NULLS FIRST;
+	`
+
+	rows, err := r.db.QueryContext(ctx, languageQuery, params.UserID)
+	if err != nil {
+		return HistoryAggregate{}, fmt.Errorf("query language stats: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			language sql.NullString
+			isTotal  bool
+			stats    HistoryStats
+		)
+
+		if err := rows.Scan(append([]any{&language, &isTotal}, stats.scanTargets()...)...); err != nil {
+			return HistoryAggregate{}, fmt.Errorf("scan language stats: %w", err)
+		}
+
+		if isTotal {
+			aggregate.Totals = stats
+			continue
+		}
+
+		aggregate.Languages = append(aggregate.Languages, LanguageStats{Language: language.String, HistoryStats: stats})
+	}
+
+	if err := rows.Err(); err != nil {
+		return HistoryAggregate{}, fmt.Errorf("iterate language stats: %w", err)
+	}
+
+	// Buckets are truncated in the requested time zone, then converted back to an absolute timestamp.
+	periodQuery := `
+		WITH bounds AS (
+			SELECT date_trunc($2, NOW() AT TIME ZONE $3) - ($4::int - 1) * ('1 ' || $2)::interval AS since
+		)
+		SELECT date_trunc($2, completed_at AT TIME ZONE $3) AT TIME ZONE $3 AS period_start, ` + statsColumns + `
+		FROM practice_history, bounds
+		WHERE user_id = $1
+		  AND completed_at >= bounds.since AT TIME ZONE $3
+		GROUP BY period_start
+		ORDER BY period_start DESC;
+	`
+
+	periodRows, err := r.db.QueryContext(ctx, periodQuery, params.UserID, params.Bucket, params.TimeZone, params.Periods)
+	if err != nil {
+		return HistoryAggregate{}, fmt.Errorf("query period stats: %w", err)
+	}
+	defer periodRows.Close()
+
+	for periodRows.Next() {
+		var period PeriodStats
+		if err := periodRows.Scan(append([]any{&period.Start}, period.scanTargets()...)...); err != nil {
+			return HistoryAggregate{}, fmt.Errorf("scan period stats: %w", err)
+		}
+
+		aggregate.Periods = append(aggregate.Periods, period)
+	}
+
+	if err := periodRows.Err(); err != nil {
+		return HistoryAggregate{}, fmt.Errorf("iterate period stats: %w", err)
+	}
+
+	return aggregate, nil
+}
+
+// scanTargets returns pointers matching the column order of statsColumns.
+func (s *HistoryStats) scanTargets() []any {
+	return []any{
+		&s.Runs,
+		&s.TotalSeconds,
+		&s.TotalErrors,
+		&s.AverageWPM,
+		&s.AverageAccuracy,
+		&s.BestWPM,
+		&s.BestAccuracy,
+	}
+}
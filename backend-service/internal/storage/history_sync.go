@@ -0,0 +1,67 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// CreateSynced inserts a run queued offline, identified by params.ClientID.
+// If the user already synced a run with that client ID, nothing is written and the stored entry
+// is returned with true, so a batch can safely be sent again after a lost response.
+func (r *HistoryRepository) CreateSynced(ctx context.Context, params CreateHistoryParams) (HistoryEntry, bool, error) {
+	if params.ClientID == "" {
+		return HistoryEntry{}, false, errors.New("synced history entry requires a client ID")
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return HistoryEntry{}, false, fmt.Errorf("begin sync history transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	entry, err := insertHistoryEntry(ctx, tx, params)
+	if errors.Is(err, ErrConflict) {
+		if err := tx.Rollback(); err != nil {
+			return HistoryEntry{}, false, fmt.Errorf("roll back synced history entry: %w", err)
+		}
+
+		entry, err = r.getByClientID(ctx, params.UserID, params.ClientID)
+		if err != nil {
+			return HistoryEntry{}, false, err
+		}
+		return entry, true, nil
+	}
+	if err != nil {
+		return HistoryEntry{}, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return HistoryEntry{}, false, fmt.Errorf("commit synced history entry: %w", err)
+	}
+
+	for _, hook := range r.createHooks {
+		hook(entry)
+	}
+
+	return entry, false, nil
+}
+
+func (r *HistoryRepository) getByClientID(ctx context.Context, userID, clientID string) (HistoryEntry, error) {
+	query := `
+		SELECT ` + historyColumns + `
+		FROM practice_history
+		WHERE user_id = $1 AND client_id = $2;
+	`
+
+	entry, err := scanHistoryEntry(r.db.QueryRowContext(ctx, query, userID, clientID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return HistoryEntry{}, ErrNotFound
+	}
+	if err != nil {
+		return HistoryEntry{}, fmt.Errorf("scan synced history entry: %w", err)
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Leaderboard periods and scopes match the keys of the leaderboard_entries materialized view.
+const (
+	LeaderboardDaily   = "daily"
+	LeaderboardWeekly  = "weekly"
+	LeaderboardAllTime = "all_time"
+
+	LeaderboardScopeLanguage = "language"
+	LeaderboardScopeSnippet  = "snippet"
+)
+
+// leaderboardRefreshLock is the advisory lock key that serializes refreshes across replicas.
+const leaderboardRefreshLock = 7_231_001
+
+// IsLeaderboardPeriod reports whether period names a supported board window.
+func IsLeaderboardPeriod(period string) bool {
+	switch period {
+	case LeaderboardDaily, LeaderboardWeekly, LeaderboardAllTime:
+		return true
+	default:
+		return false
+	}
+}
+
+// LeaderboardQuery selects one board. Key is the language for the language scope
+// and the snippet hash for the snippet scope.
+type LeaderboardQuery struct {
+	Period string
+	Scope  string
+	Key    string
+	Limit  int
+	Offset int
+}
+
+// LeaderboardEntry is a user's best verified run on a board.
+// It deliberately carries no user identifier other than the opt-in display name.
+type LeaderboardEntry struct {
+	Rank        int
+	DisplayName string
+	HistoryID   string
+	Language    string
+	WPM         int
+	Accuracy    int
+	CompletedAt time.Time
+}
+
+// LeaderboardRepository reads and refreshes the precomputed leaderboards.
+type LeaderboardRepository struct {
+	db *sql.DB
+}
+
+// NewLeaderboardRepository creates a new LeaderboardRepository.
+func NewLeaderboardRepository(db *sql.DB) *LeaderboardRepository {
+	return &LeaderboardRepository{db: db}
+}
+
+// List returns one page of a board, ranked by WPM, then accuracy, then the earlier run.
+// Only users who opted in with a display name are listed; ranks are contiguous among them.
+func (r *LeaderboardRepository) List(ctx context.Context, q LeaderboardQuery) ([]LeaderboardEntry, error) {
+	const query = `
+		SELECT p.display_name, e.history_id, e.language, e.wpm, e.accuracy, e.completed_at
+		FROM leaderboard_entries e
+		JOIN user_profiles p ON p.user_id = e.user_id AND p.leaderboard_opt_in
+		WHERE e.period = $1 AND e.scope = $2 AND e.scope_key = $3
+		ORDER BY e.wpm DESC, e.accuracy DESC, e.completed_at ASC, e.history_id
+		LIMIT $4 OFFSET $5;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, q.Period, q.Scope, q.Key, q.Limit, q.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("query leaderboard: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]LeaderboardEntry, 0)
+	for rows.Next() {
+		entry := LeaderboardEntry{Rank: q.Offset + len(entries) + 1}
+		if err := rows.Scan(&entry.DisplayName, &entry.HistoryID, &entry.Language, &entry.WPM, &entry.Accuracy, &entry.CompletedAt); err != nil {
+			return nil, fmt.Errorf("scan leaderboard entry: %w", err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate leaderboard: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Refresh recomputes the boards without blocking readers.
+// When another replica is already refreshing, Refresh returns without doing anything.
+func (r *LeaderboardRepository) Refresh(ctx context.Context) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1);`, leaderboardRefreshLock).Scan(&locked); err != nil {
+		return fmt.Errorf("acquire refresh lock: %w", err)
+	}
+
+	if !locked {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `REFRESH MATERIALIZED VIEW CONCURRENTLY leaderboard_entries;`); err != nil {
+		return fmt.Errorf("refresh leaderboards: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit refresh: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Preferences holds per-user UI settings shared across devices.
+// An empty DefaultLanguage lets the client choose.
+type Preferences struct {
+	Theme           string
+	DefaultLanguage string
+	SnippetLength   string
+	ShowLiveStats   bool
+	UpdatedAt       time.Time
+}
+
+// DefaultPreferences are returned for users who never saved preferences.
+var DefaultPreferences = Preferences{
+	Theme:         "system",
+	SnippetLength: "any",
+	ShowLiveStats: true,
+}
+
+// PreferencesRepository handles persistence of user preferences.
+type PreferencesRepository struct {
+	db *sql.DB
+}
+
+// NewPreferencesRepository creates a new PreferencesRepository.
+func NewPreferencesRepository(db *sql.DB) *PreferencesRepository {
+	return &PreferencesRepository{db: db}
+}
+
+// Get returns the user's preferences, or DefaultPreferences if none were saved.
+func (r *PreferencesRepository) Get(ctx context.Context, userID string) (Preferences, error) {
+	const query = `
+		SELECT theme, default_language, snippet_length, show_live_stats, updated_at
+		FROM user_preferences
+		WHERE user_id = $1;
+	`
+
+	prefs, err := scanPreferences(r.db.QueryRowContext(ctx, query, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return DefaultPreferences, nil
+	}
+	if err != nil {
+		return Preferences{}, fmt.Errorf("scan preferences: %w", err)
+	}
+
+	return prefs, nil
+}
+
+// Save creates or replaces the user's preferences and returns the stored record.
+func (r *PreferencesRepository) Save(ctx context.Context, userID string, prefs Preferences) (Preferences, error) {
+	const query = `
+		INSERT INTO user_preferences (user_id, theme, default_language, snippet_length, show_live_stats)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET theme = EXCLUDED.theme,
+		    default_language = EXCLUDED.default_language,
+		    snippet_length = EXCLUDED.snippet_length,
+		    show_live_stats = EXCLUDED.show_live_stats,
+		    updated_at = NOW()
+		RETURNING theme, default_language, snippet_length, show_live_stats, updated_at;
+	`
+
+	saved, err := scanPreferences(r.db.QueryRowContext(ctx, query,
+		userID,
+		prefs.Theme,
+		prefs.DefaultLanguage,
+		prefs.SnippetLength,
+		prefs.ShowLiveStats,
+	))
+	if err != nil {
+		return Preferences{}, fmt.Errorf("save preferences: %w", err)
+	}
+
+	return saved, nil
+}
+
+// deletePreferencesByUser removes the user's preferences, if any.
+func deletePreferencesByUser(ctx context.Context, tx dbtx, userID string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_preferences WHERE user_id = $1;`, userID); err != nil {
+		return fmt.Errorf("delete preferences: %w", err)
+	}
+
+	return nil
+}
+
+func scanPreferences(row rowScanner) (Preferences, error) {
+	var prefs Preferences
+	err := row.Scan(&prefs.Theme, &prefs.DefaultLanguage, &prefs.SnippetLength, &prefs.ShowLiveStats, &prefs.UpdatedAt)
+	return prefs, err
+}
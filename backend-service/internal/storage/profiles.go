@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Profile holds the public-facing settings of a user.
+// DisplayName is the only identity shown on leaderboards, and only when LeaderboardOptIn is set.
+type Profile struct {
+	UserID           string
+	DisplayName      string
+	LeaderboardOptIn bool
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// ProfileParams contains the editable fields of a profile.
+type ProfileParams struct {
+	DisplayName      string
+	LeaderboardOptIn bool
+}
+
+// ProfileRepository handles persistence of user profiles.
+type ProfileRepository struct {
+	db *sql.DB
+}
+
+// NewProfileRepository creates a new ProfileRepository.
+func NewProfileRepository(db *sql.DB) *ProfileRepository {
+	return &ProfileRepository{db: db}
+}
+
+// Get returns the user's profile.
+// Returns ErrNotFound if the user has not created one.
+func (r *ProfileRepository) Get(ctx context.Context, userID string) (Profile, error) {
+	const query = `
+		SELECT user_id, display_name, leaderboard_opt_in, created_at, updated_at
+		FROM user_profiles
+		WHERE user_id = $1;
+	`
+
+	profile, err := scanProfile(r.db.QueryRowContext(ctx, query, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Profile{}, ErrNotFound
+	}
+	if err != nil {
+		return Profile{}, fmt.Errorf("scan profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+// Upsert creates or replaces the user's profile.
+// Returns ErrConflict if another user already uses the display name (case-insensitively).
+func (r *ProfileRepository) Upsert(ctx context.Context, userID string, params ProfileParams) (Profile, error) {
+	const query = `
+		INSERT INTO user_profiles (user_id, display_name, leaderboard_opt_in)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET display_name = EXCLUDED.display_name,
+		    leaderboard_opt_in = EXCLUDED.leaderboard_opt_in,
+		    updated_at = NOW()
+		RETURNING user_id, display_name, leaderboard_opt_in, created_at, updated_at;
+	`
+
+	profile, err := scanProfile(r.db.QueryRowContext(ctx, query, userID, params.DisplayName, params.LeaderboardOptIn))
+	if isUniqueViolation(err) {
+		return Profile{}, ErrConflict
+	}
+	if err != nil {
+		return Profile{}, fmt.Errorf("upsert profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+// deleteProfileByUser removes the user's profile, if any.
+func deleteProfileByUser(ctx context.Context, tx dbtx, userID string) error {
+	const query = `
+		DELETE FROM user_profiles
+		WHERE user_id = $1;
+	`
+
+	if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("delete profile: %w", err)
+	}
+
+	return nil
+}
+
+func scanProfile(row rowScanner) (Profile, error) {
+	var profile Profile
+	err := row.Scan(&profile.UserID, &profile.DisplayName, &profile.LeaderboardOptIn, &profile.CreatedAt, &profile.UpdatedAt)
+	return profile, err
+}
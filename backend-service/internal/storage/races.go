@@ -0,0 +1,386 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Race statuses. A race moves from waiting through countdown and running to finished;
+// rooms that are abandoned, or lost when their instance restarts or stops renewing its lease, end as expired.
+const (
+	RaceWaiting   = "waiting"
+	RaceCountdown = "countdown"
+	RaceRunning   = "running"
+	RaceFinished  = "finished"
+	RaceExpired   = "expired"
+)
+
+// ErrRaceClosed is returned when joining a race that already started or is full.
+var ErrRaceClosed = errors.New("race is not accepting participants")
+
+// Race is a multiplayer room in which participants type the same snippet.
+// InstanceID names the backend instance that runs the room, which renewed its lease at HeartbeatAt.
+type Race struct {
+	ID          string
+	Code        string
+	OwnerID     string
+	SnippetID   string
+	SnippetHash string
+	Language    string
+	Status      string
+	InstanceID  string
+	HeartbeatAt time.Time
+	CreatedAt   time.Time
+	StartedAt   *time.Time
+	FinishedAt  *time.Time
+}
+
+// Open reports whether the race has not ended yet.
+func (r Race) Open() bool {
+	return r.Status == RaceWaiting || r.Status == RaceCountdown || r.Status == RaceRunning
+}
+
+// CreateRaceParams contains parameters to open a new race.
+// InstanceID names the backend instance that runs the race's room.
+type CreateRaceParams struct {
+	Code        string
+	InstanceID  string
+	OwnerID     string
+	SnippetID   string
+	SnippetHash string
+	Language    string
+}
+
+// RaceParticipant is a user who joined a race. DisplayName is empty when the user has no profile.
+type RaceParticipant struct {
+	UserID      string
+	DisplayName string
+	JoinedAt    time.Time
+}
+
+// RaceResult is a finished run recorded for a race.
+type RaceResult struct {
+	UserID          string
+	HistoryID       string
+	WPM             int
+	Accuracy        int
+	Errors          int
+	DurationSeconds int
+	CompletedAt     time.Time
+}
+
+// RaceRepository handles persistence of races and their participants.
+type RaceRepository struct {
+	db *sql.DB
+}
+
+// NewRaceRepository creates a new RaceRepository.
+func NewRaceRepository(db *sql.DB) *RaceRepository {
+	return &RaceRepository{db: db}
+}
+
+const raceColumns = `id, code, owner_id, snippet_id, snippet_hash, language, status, instance_id, heartbeat_at, created_at, started_at, finished_at`
+
+// Create inserts a race and registers its owner as the first participant.
+// Returns ErrConflict if the join code is already in use.
+func (r *RaceRepository) Create(ctx context.Context, params CreateRaceParams) (Race, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Race{}, fmt.Errorf("begin create race transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO races (code, owner_id, snippet_id, snippet_hash, language, instance_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + raceColumns + `;
+	`
+
+	race, err := scanRace(tx.QueryRowContext(ctx, query,
+		params.Code,
+		params.OwnerID,
+		nullString(params.SnippetID),
+		params.SnippetHash,
+		params.Language,
+		params.InstanceID,
+	))
+	if isUniqueViolation(err) {
+		return Race{}, ErrConflict
+	}
+	if err != nil {
+		return Race{}, fmt.Errorf("insert race: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO race_participants (race_id, user_id) VALUES ($1, $2);`, race.ID, params.OwnerID); err != nil {
+		return Race{}, fmt.Errorf("insert race owner: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Race{}, fmt.Errorf("commit race: %w", err)
+	}
+
+	return race, nil
+}
+
+// GetByID returns a race. Returns ErrNotFound if it does not exist.
+func (r *RaceRepository) GetByID(ctx context.Context, id string) (Race, error) {
+	return r.getBy(ctx, "id", id)
+}
+
+// GetByCode returns the race with the join code. Returns ErrNotFound if it does not exist.
+func (r *RaceRepository) GetByCode(ctx context.Context, code string) (Race, error) {
+	return r.getBy(ctx, "code", code)
+}
+
+func (r *RaceRepository) getBy(ctx context.Context, column, value string) (Race, error) {
+	query := `
+		SELECT ` + raceColumns + `
+		FROM races
+		WHERE ` + column + ` = $1;
+	`
+
+	race, err := scanRace(r.db.QueryRowContext(ctx, query, value))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Race{}, ErrNotFound
+	}
+	if err != nil {
+		return Race{}, fmt.Errorf("scan race: %w", err)
+	}
+
+	return race, nil
+}
+
+// AddParticipant registers the user in a waiting race with fewer than limit participants.
+// Joining a race twice is a no-op. Returns ErrNotFound for unknown races and ErrRaceClosed
+// when the race already started or is full.
+func (r *RaceRepository) AddParticipant(ctx context.Context, raceID, userID string, limit int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin join race transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Locking the race row serializes concurrent joins so the limit holds.
+	var status string
+	err = tx.QueryRowContext(ctx, `SELECT status FROM races WHERE id = $1 FOR UPDATE;`, raceID).Scan(&status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("lock race: %w", err)
+	}
+
+	var joined bool
+	var count int
+	const countQuery = `
+		SELECT COALESCE(BOOL_OR(user_id = $2), FALSE), COUNT(*)::int
+		FROM race_participants
+		WHERE race_id = $1;
+	`
+	if err := tx.QueryRowContext(ctx, countQuery, raceID, userID).Scan(&joined, &count); err != nil {
+		return fmt.Errorf("count race participants: %w", err)
+	}
+
+	if joined {
+		return nil
+	}
+
+	if status != RaceWaiting || count >= limit {
+		return ErrRaceClosed
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO race_participants (race_id, user_id) VALUES ($1, $2);`, raceID, userID); err != nil {
+		return fmt.Errorf("insert race participant: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit race participant: %w", err)
+	}
+
+	return nil
+}
+
+// Participants returns the users in a race in join order.
+func (r *RaceRepository) Participants(ctx context.Context, raceID string) ([]RaceParticipant, error) {
+	const query = `
+		SELECT rp.user_id, COALESCE(p.display_name, ''), rp.joined_at
+		FROM race_participants rp
+		LEFT JOIN user_profiles p ON p.user_id = rp.user_id
+		WHERE rp.race_id = $1
+		ORDER BY rp.joined_at, rp.user_id;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, raceID)
+	if err != nil {
+		return nil, fmt.Errorf("query race participants: %w", err)
+	}
+	defer rows.Close()
+
+	participants := make([]RaceParticipant, 0)
+	for rows.Next() {
+		var participant RaceParticipant
+		if err := rows.Scan(&participant.UserID, &participant.DisplayName, &participant.JoinedAt); err != nil {
+			return nil, fmt.Errorf("scan race participant: %w", err)
+		}
+
+		participants = append(participants, participant)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate race participants: %w", err)
+	}
+
+	return participants, nil
+}
+
+// SetStatus moves a race to the given status, stamping started_at when it starts running
+// and finished_at when it ends.
+func (r *RaceRepository) SetStatus(ctx context.Context, raceID, status string) error {
+	const query = `
+		UPDATE races
+		SET status = $2,
+		    started_at = CASE WHEN $2 = 'running' THEN NOW() ELSE started_at END,
+		    finished_at = CASE WHEN $2 IN ('finished', 'expired') THEN NOW() ELSE finished_at END
+		WHERE id = $1;
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, raceID, status); err != nil {
+		return fmt.Errorf("update race status: %w", err)
+	}
+
+	return nil
+}
+
+// Heartbeat renews the lease of every open race whose room runs on the instance.
+func (r *RaceRepository) Heartbeat(ctx context.Context, instanceID string) error {
+	const query = `
+		UPDATE races
+		SET heartbeat_at = NOW()
+		WHERE instance_id = $1 AND status IN ('waiting', 'countdown', 'running');
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, instanceID); err != nil {
+		return fmt.Errorf("renew race leases: %w", err)
+	}
+
+	return nil
+}
+
+// ExpireInstance marks the open races of the instance as expired. Rooms live in memory,
+// so races left open by a previous process with the same instance ID can never finish.
+func (r *RaceRepository) ExpireInstance(ctx context.Context, instanceID string) error {
+	const query = `
+		UPDATE races
+		SET status = 'expired', finished_at = NOW()
+		WHERE instance_id = $1 AND status IN ('waiting', 'countdown', 'running');
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, instanceID); err != nil {
+		return fmt.Errorf("expire instance races: %w", err)
+	}
+
+	return nil
+}
+
+// ExpireStale marks open races whose lease was not renewed within lease as expired;
+// the instance running their rooms is gone.
+func (r *RaceRepository) ExpireStale(ctx context.Context, lease time.Duration) error {
+	const query = `
+		UPDATE races
+		SET status = 'expired', finished_at = NOW()
+		WHERE status IN ('waiting', 'countdown', 'running')
+		  AND heartbeat_at < NOW() - make_interval(secs => $1);
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, lease.Seconds()); err != nil {
+		return fmt.Errorf("expire stale races: %w", err)
+	}
+
+	return nil
+}
+
+// Results returns the runs recorded for a race, fastest first.
+func (r *RaceRepository) Results(ctx context.Context, raceID string) ([]RaceResult, error) {
+	const query = `
+		SELECT user_id, id, wpm, accuracy, errors, duration_seconds, completed_at
+		FROM practice_history
+		WHERE race_id = $1
+		ORDER BY completed_at, id;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, raceID)
+	if err != nil {
+		return nil, fmt.Errorf("query race results: %w", err)
+	}
+	defer rows.Close()
+
+	results := make([]RaceResult, 0)
+	for rows.Next() {
+		var result RaceResult
+		if err := rows.Scan(&result.UserID, &result.HistoryID, &result.WPM, &result.Accuracy, &result.Errors, &result.DurationSeconds, &result.CompletedAt); err != nil {
+			return nil, fmt.Errorf("scan race result: %w", err)
+		}
+
+		results = append(results, result)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate race results: %w", err)
+	}
+
+	return results, nil
+}
+
+// deleteRacesByUser removes the races the user owns and the user's participation in others.
+func deleteRacesByUser(ctx context.Context, tx dbtx, userID string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM races WHERE owner_id = $1;`, userID); err != nil {
+		return fmt.Errorf("delete races: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM race_participants WHERE user_id = $1;`, userID); err != nil {
+		return fmt.Errorf("delete race participations: %w", err)
+	}
+
+	return nil
+}
+
+func scanRace(row rowScanner) (Race, error) {
+	var (
+		race       Race
+		snippetID  sql.NullString
+		instanceID sql.NullString
+		startedAt  sql.NullTime
+		finishedAt sql.NullTime
+	)
+
+	if err := row.Scan(
+		&race.ID,
+		&race.Code,
+		&race.OwnerID,
+		&snippetID,
+		&race.SnippetHash,
+		&race.Language,
+		&race.Status,
+		&instanceID,
+		&race.HeartbeatAt,
+		&race.CreatedAt,
+		&startedAt,
+		&finishedAt,
+	); err != nil {
+		return Race{}, err
+	}
+
+	race.SnippetID = snippetID.String
+	race.InstanceID = instanceID.String
+	if startedAt.Valid {
+		race.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		race.FinishedAt = &finishedAt.Time
+	}
+
+	return race, nil
+}
@@ -0,0 +1,405 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Snippet represents a code sample from the practice catalog.
+type Snippet struct {
+	ID         string
+	Language   string
+	Title      string
+	Difficulty string
+	Tags       []string
+	Content    string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// ContentHash returns the hex-encoded SHA-256 of snippet text with LF line endings.
+// History entries use it to group runs of the same code, including snippets outside the catalog.
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(strings.ReplaceAll(content, "\r\n", "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// SnippetParams contains the editable fields of a snippet.
+type SnippetParams struct {
+	Language   string
+	Title      string
+	Difficulty string
+	Tags       []string
+	Content    string
+}
+
+// SnippetFilter narrows catalog queries. Empty fields are ignored.
+// Tags match snippets that contain all of the listed tags.
+type SnippetFilter struct {
+	Language   string
+	Difficulty string
+	Tags       []string
+	Limit      int
+	Offset     int
+}
+
+// SnippetRepository handles persistence of the snippet catalog.
+type SnippetRepository struct {
+	db *sql.DB
+}
+
+// NewSnippetRepository creates a new SnippetRepository.
+func NewSnippetRepository(db *sql.DB) *SnippetRepository {
+	return &SnippetRepository{db: db}
+}
+
+const snippetColumns = `id, language, title, difficulty, array_to_json(tags)::text, content, created_at, updated_at`
+
+// Create inserts a new snippet and returns the stored record.
+func (r *SnippetRepository) Create(ctx context.Context, params SnippetParams) (Snippet, error) {
+	query := `
+		INSERT INTO snippets (language, title, difficulty, tags, content, content_hash)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + snippetColumns + `;
+	`
+
+	row := r.db.QueryRowContext(ctx, query,
+		params.Language,
+		params.Title,
+		params.Difficulty,
+		normalizeTags(params.Tags),
+		params.Content,
+		ContentHash(params.Content),
+	)
+
+	snippet, err := scanSnippet(row)
+	if err != nil {
+		return Snippet{}, fmt.Errorf("scan inserted snippet: %w", err)
+	}
+
+	return snippet, nil
+}
+
+// Update replaces the editable fields of an existing snippet.
+// Returns ErrNotFound if the snippet does not exist.
+func (r *SnippetRepository) Update(ctx context.Context, id string, params SnippetParams) (Snippet, error) {
+	query := `
+		UPDATE snippets
+		SET language = $2, title = $3, difficulty = $4, tags = $5, content = $6, content_hash = $7, updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + snippetColumns + `;
+	`
+
+	row := r.db.QueryRowContext(ctx, query,
+		id,
+		params.Language,
+		params.Title,
+		params.Difficulty,
+		normalizeTags(params.Tags),
+		params.Content,
+		ContentHash(params.Content),
+	)
+
+	snippet, err := scanSnippet(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Snippet{}, ErrNotFound
+	}
+	if err != nil {
+		return Snippet{}, fmt.Errorf("scan updated snippet: %w", err)
+	}
+
+	return snippet, nil
+}
+
+// Delete removes a snippet from the catalog.
+// Returns ErrNotFound if the snippet does not exist.
+func (r *SnippetRepository) Delete(ctx context.Context, id string) error {
+	const query = `
+		DELETE FROM snippets
+		WHERE id = $1;
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("delete snippet: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("read deleted snippet count: %w", err)
+	}
+
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// GetByID returns a single snippet. Returns ErrNotFound if the snippet does not exist.
+func (r *SnippetRepository) GetByID(ctx context.Context, id string) (Snippet, error) {
+	query := `
+		SELECT ` + snippetColumns + `
+		FROM snippets
+		WHERE id = $1;
+	`
+
+	snippet, err := scanSnippet(r.db.QueryRowContext(ctx, query, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Snippet{}, ErrNotFound
+	}
+	if err != nil {
+		return Snippet{}, fmt.Errorf("scan snippet: %w", err)
+	}
+
+	return snippet, nil
+}
+
+// GetByIDs returns the snippets with the given IDs that exist, in no particular order.
+// IDs must be UUIDs.
+func (r *SnippetRepository) GetByIDs(ctx context.Context, ids []string) ([]Snippet, error) {
+	query := `
+		SELECT ` + snippetColumns + `
+		FROM snippets
+		WHERE id = ANY($1::text[]::uuid[]);
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("query snippets by id: %w", err)
+	}
+	defer rows.Close()
+
+	snippets := make([]Snippet, 0, len(ids))
+	for rows.Next() {
+		snippet, err := scanSnippet(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan snippet: %w", err)
+		}
+
+		snippets = append(snippets, snippet)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate snippets: %w", err)
+	}
+
+	return snippets, nil
+}
+
+// CatalogHashes returns which of the given content hashes belong to catalog snippets.
+func (r *SnippetRepository) CatalogHashes(ctx context.Context, hashes []string) (map[string]bool, error) {
+	const query = `
+		SELECT DISTINCT content_hash
+		FROM snippets
+		WHERE content_hash = ANY($1::text[]);
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, hashes)
+	if err != nil {
+		return nil, fmt.Errorf("query snippet hashes: %w", err)
+	}
+	defer rows.Close()
+
+	catalog := make(map[string]bool)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("scan snippet hash: %w", err)
+		}
+
+		catalog[hash] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate snippet hashes: %w", err)
+	}
+
+	return catalog, nil
+}
+
+// IsCatalogHash reports whether a catalog snippet has the given content hash.
+func (r *SnippetRepository) IsCatalogHash(ctx context.Context, hash string) (bool, error) {
+	const query = `
+		SELECT EXISTS (
+			SELECT 1
+			FROM snippets
+			WHERE content_hash = $1
+		);
+	`
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, hash).Scan(&exists); err != nil {
+		return false, fmt.Errorf("look up snippet hash: %w", err)
+	}
+
+	return exists, nil
+}
+
+// List returns catalog snippets matching the filter ordered by language and title.
+func (r *SnippetRepository) List(ctx context.Context, filter SnippetFilter) ([]Snippet, error) {
+	where, args := snippetFilterClause(filter)
+	args = append(args, filter.Limit, filter.Offset)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM snippets
+		%s
+		ORDER BY language, title, id
+		LIMIT $%d OFFSET $%d;
+	`, snippetColumns, where, len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query snippets: %w", err)
+	}
+	defer rows.Close()
+
+	snippets := make([]Snippet, 0)
+	for rows.Next() {
+		snippet, err := scanSnippet(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan snippet: %w", err)
+		}
+
+		snippets = append(snippets, snippet)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate snippets: %w", err)
+	}
+
+	return snippets, nil
+}
+
+// Random returns a random snippet matching the filter.
+// Returns ErrNotFound if no snippet matches.
+func (r *SnippetRepository) Random(ctx context.Context, filter SnippetFilter) (Snippet, error) {
+	where, args := snippetFilterClause(filter)
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM snippets
+		%s
+		ORDER BY random()
+		LIMIT 1;
+	`, snippetColumns, where)
+
+	snippet, err := scanSnippet(r.db.QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Snippet{}, ErrNotFound
+	}
+	if err != nil {
+		return Snippet{}, fmt.Errorf("scan random snippet: %w", err)
+	}
+
+	return snippet, nil
+}
+
+// Languages returns the distinct languages present in the catalog.
+func (r *SnippetRepository) Languages(ctx context.Context) ([]string, error) {
+	const query = `
+		SELECT DISTINCT language
+		FROM snippets
+		ORDER BY language;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query snippet languages: %w", err)
+	}
+	defer rows.Close()
+
+	languages := make([]string, 0)
+	for rows.Next() {
+		var language string
+		if err := rows.Scan(&language); err != nil {
+			return nil, fmt.Errorf("scan snippet language: %w", err)
+		}
+
+		languages = append(languages, language)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate snippet languages: %w", err)
+	}
+
+	return languages, nil
+}
+
+// snippetFilterClause builds a parameterized WHERE clause for the filter.
+func snippetFilterClause(filter SnippetFilter) (string, []any) {
+	conditions := make([]string, 0, 3)
+	args := make([]any, 0, 3)
+
+	if filter.Language != "" {
+		args = append(args, filter.Language)
+		conditions = append(conditions, fmt.Sprintf("language = $%d", len(args)))
+	}
+
+	if filter.Difficulty != "" {
+		args = append(args, filter.Difficulty)
+		conditions = append(conditions, fmt.Sprintf("difficulty = $%d", len(args)))
+	}
+
+	if tags := normalizeTags(filter.Tags); len(tags) > 0 {
+		args = append(args, tags)
+		conditions = append(conditions, fmt.Sprintf("tags @> $%d", len(args)))
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// normalizeTags lowercases and de-duplicates tags so filters match consistently.
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	return normalized
+}
+
+func scanSnippet(row rowScanner) (Snippet, error) {
+	var (
+		snippet Snippet
+		tags    string
+	)
+
+	if err := row.Scan(
+		&snippet.ID,
+		&snippet.Language,
+		&snippet.Title,
+		&snippet.Difficulty,
+		&tags,
+		&snippet.Content,
+		&snippet.CreatedAt,
+		&snippet.UpdatedAt,
+	); err != nil {
+		return Snippet{}, err
+	}
+
+	if err := json.Unmarshal([]byte(tags), &snippet.Tags); err != nil {
+		return Snippet{}, fmt.Errorf("decode snippet tags: %w", err)
+	}
+
+	return snippet, nil
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+)
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// dbtx is implemented by *sql.DB and *sql.Tx so helpers can run inside or outside a transaction.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// extendedRow scans columns selected after the ones a scan helper knows about.
+type extendedRow struct {
+	row   rowScanner
+	extra []any
+}
+
+func (e extendedRow) Scan(dest ...any) error {
+	return e.row.Scan(append(dest, e.extra...)...)
+}
@@ -0,0 +1,604 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Team roles, from most to least privileged. Every team has exactly one owner.
+const (
+	TeamRoleOwner  = "owner"
+	TeamRoleAdmin  = "admin"
+	TeamRoleMember = "member"
+)
+
+// TeamRoleAtLeast reports whether role grants at least the privileges of minimum.
+// Unknown roles grant nothing.
+func TeamRoleAtLeast(role, minimum string) bool {
+	rank := map[string]int{TeamRoleMember: 1, TeamRoleAdmin: 2, TeamRoleOwner: 3}
+	return rank[role] > 0 && rank[role] >= rank[minimum]
+}
+
+// Team is a group of users who share a dashboard and leaderboard.
+type Team struct {
+	ID         string
+	Name       string
+	InviteCode string
+	CreatedAt  time.Time
+}
+
+// TeamMembership is a team as seen by one of its members.
+type TeamMembership struct {
+	Team
+	Role        string
+	MemberCount int
+}
+
+// CreateTeamParams contains parameters to create a team.
+type CreateTeamParams struct {
+	Name       string
+	InviteCode string
+	OwnerID    string
+}
+
+// TeamMember is a user in a team. ID identifies the membership and is safe to show to teammates;
+// Ordinal is the member's position in join order. DisplayName is empty when the user has no profile.
+type TeamMember struct {
+	ID          string
+	UserID      string
+	DisplayName string
+	Role        string
+	Ordinal     int
+	JoinedAt    time.Time
+}
+
+// TeamMemberStats holds a member's aggregates for a dashboard period.
+type TeamMemberStats struct {
+	TeamMember
+	HistoryStats
+}
+
+// TeamDashboard holds aggregates of the whole team and of every member for a period.
+type TeamDashboard struct {
+	Totals  HistoryStats
+	Members []TeamMemberStats
+}
+
+// TeamLeaderboardQuery selects a team board. Period is one of the leaderboard periods;
+// an empty Language ranks runs in every language.
+type TeamLeaderboardQuery struct {
+	TeamID   string
+	Period   string
+	Language string
+}
+
+// TeamLeaderboardEntry is a member's best verified run in the board's period.
+type TeamLeaderboardEntry struct {
+	Rank        int
+	Member      TeamMember
+	HistoryID   string
+	Language    string
+	WPM         int
+	Accuracy    int
+	CompletedAt time.Time
+}
+
+// TeamRepository handles persistence of teams and their members.
+type TeamRepository struct {
+	db *sql.DB
+}
+
+// NewTeamRepository creates a new TeamRepository.
+func NewTeamRepository(db *sql.DB) *TeamRepository {
+	return &TeamRepository{db: db}
+}
+
+// teamMembersCTE numbers the members of team $1 in join order.
+const teamMembersCTE = `
+	WITH members AS (
+		SELECT m.id, m.user_id, COALESCE(p.display_name, '') AS display_name, m.role,
+		       ROW_NUMBER() OVER (ORDER BY m.joined_at, m.id)::int AS ordinal, m.joined_at
+		FROM team_members m
+		LEFT JOIN user_profiles p ON p.user_id = m.user_id
+		WHERE m.team_id = $1
+	)`
+
+// Create inserts a team with the user as its owner.
+// Returns ErrConflict if the invite code is already in use.
+func (r *TeamRepository) Create(ctx context.Context, params CreateTeamParams) (TeamMembership, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return TeamMembership{}, fmt.Errorf("begin create team transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	membership := TeamMembership{Role: TeamRoleOwner, MemberCount: 1}
+
+	const query = `
+		INSERT INTO teams (name, invite_code)
+		VALUES ($1, $2)
+		RETURNING id, name, invite_code, created_at;
+	`
+
+	err = tx.QueryRowContext(ctx, query, params.Name, params.InviteCode).Scan(
+		&membership.ID,
+		&membership.Name,
+		&membership.InviteCode,
+		&membership.CreatedAt,
+	)
+	if isUniqueViolation(err) {
+		return TeamMembership{}, ErrConflict
+	}
+	if err != nil {
+		return TeamMembership{}, fmt.Errorf("insert team: %w", err)
+	}
+
+	const ownerQuery = `INSERT INTO team_members (team_id, user_id, role) VALUES ($1, $2, 'owner');`
+	if _, err := tx.ExecContext(ctx, ownerQuery, membership.ID, params.OwnerID); err != nil {
+		return TeamMembership{}, fmt.Errorf("insert team owner: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return TeamMembership{}, fmt.Errorf("commit team: %w", err)
+	}
+
+	return membership, nil
+}
+
+const teamMembershipQuery = `
+	SELECT t.id, t.name, t.invite_code, t.created_at, m.role,
+	       (SELECT COUNT(*)::int FROM team_members c WHERE c.team_id = t.id)
+	FROM teams t
+	JOIN team_members m ON m.team_id = t.id
+`
+
+// ListByUser returns the teams the user belongs to, ordered by name.
+func (r *TeamRepository) ListByUser(ctx context.Context, userID string) ([]TeamMembership, error) {
+	query := teamMembershipQuery + `
+		WHERE m.user_id = $1
+		ORDER BY lower(t.name), t.id;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query teams: %w", err)
+	}
+	defer rows.Close()
+
+	teams := make([]TeamMembership, 0)
+	for rows.Next() {
+		membership, err := scanTeamMembership(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan team: %w", err)
+		}
+
+		teams = append(teams, membership)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate teams: %w", err)
+	}
+
+	return teams, nil
+}
+
+// Get returns a team as seen by the user. Returns ErrNotFound if the team does not exist
+// or the user is not a member.
+func (r *TeamRepository) Get(ctx context.Context, teamID, userID string) (TeamMembership, error) {
+	query := teamMembershipQuery + `
+		WHERE t.id = $1 AND m.user_id = $2;
+	`
+
+	membership, err := scanTeamMembership(r.db.QueryRowContext(ctx, query, teamID, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return TeamMembership{}, ErrNotFound
+	}
+	if err != nil {
+		return TeamMembership{}, fmt.Errorf("scan team: %w", err)
+	}
+
+	return membership, nil
+}
+
+// Role returns the user's role in the team. Returns ErrNotFound if the user is not a member.
+func (r *TeamRepository) Role(ctx context.Context, teamID, userID string) (string, error) {
+	var role string
+	err := r.db.QueryRowContext(ctx, `SELECT role FROM team_members WHERE team_id = $1 AND user_id = $2;`, teamID, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("load team role: %w", err)
+	}
+
+	return role, nil
+}
+
+// Join adds the user as a member of the team with the invite code and returns the team ID.
+// Joining a team twice keeps the existing role. Returns ErrNotFound for unknown codes.
+func (r *TeamRepository) Join(ctx context.Context, inviteCode, userID string) (string, error) {
+	var teamID string
+	err := r.db.QueryRowContext(ctx, `SELECT id FROM teams WHERE invite_code = $1;`, inviteCode).Scan(&teamID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("load team by invite code: %w", err)
+	}
+
+	const query = `
+		INSERT INTO team_members (team_id, user_id, role)
+		VALUES ($1, $2, 'member')
+		ON CONFLICT (team_id, user_id) DO NOTHING;
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, teamID, userID); err != nil {
+		return "", fmt.Errorf("insert team member: %w", err)
+	}
+
+	return teamID, nil
+}
+
+// Leave removes the user from the team. When the owner leaves, ownership passes to the
+// longest-standing admin, or else the longest-standing member; the last member leaving deletes the team.
+// Returns ErrNotFound if the user is not a member.
+func (r *TeamRepository) Leave(ctx context.Context, teamID, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin leave team transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := leaveTeam(ctx, tx, teamID, userID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit leave team: %w", err)
+	}
+
+	return nil
+}
+
+// leaveTeam implements Leave inside the caller's transaction, which keeps the team row locked until it ends.
+func leaveTeam(ctx context.Context, tx dbtx, teamID, userID string) error {
+	// Locking the team row serializes concurrent departures so ownership is handed over once.
+	if _, err := tx.ExecContext(ctx, `SELECT id FROM teams WHERE id = $1 FOR UPDATE;`, teamID); err != nil {
+		return fmt.Errorf("lock team: %w", err)
+	}
+
+	var role string
+	err := tx.QueryRowContext(ctx, `DELETE FROM team_members WHERE team_id = $1 AND user_id = $2 RETURNING role;`, teamID, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("delete team member: %w", err)
+	}
+
+	if role == TeamRoleOwner {
+		const successorQuery = `
+			UPDATE team_members
+			SET role = 'owner'
+			WHERE id = (
+				SELECT id
+				FROM team_members
+				WHERE team_id = $1
+				ORDER BY role = 'admin' DESC, joined_at, id
+				LIMIT 1
+			);
+		`
+
+		result, err := tx.ExecContext(ctx, successorQuery, teamID)
+		if err != nil {
+			return fmt.Errorf("transfer team ownership: %w", err)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("read new team owner count: %w", err)
+		}
+
+		if affected == 0 {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM teams WHERE id = $1;`, teamID); err != nil {
+				return fmt.Errorf("delete empty team: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Members returns the members of a team in join order.
+func (r *TeamRepository) Members(ctx context.Context, teamID string) ([]TeamMember, error) {
+	query := teamMembersCTE + `
+		SELECT id, user_id, display_name, role, ordinal, joined_at
+		FROM members
+		ORDER BY ordinal;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, teamID)
+	if err != nil {
+		return nil, fmt.Errorf("query team members: %w", err)
+	}
+	defer rows.Close()
+
+	members := make([]TeamMember, 0)
+	for rows.Next() {
+		var member TeamMember
+		if err := rows.Scan(member.scanTargets()...); err != nil {
+			return nil, fmt.Errorf("scan team member: %w", err)
+		}
+
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate team members: %w", err)
+	}
+
+	return members, nil
+}
+
+// Member returns one membership of a team. Returns ErrNotFound if it does not exist.
+func (r *TeamRepository) Member(ctx context.Context, teamID, memberID string) (TeamMember, error) {
+	query := teamMembersCTE + `
+		SELECT id, user_id, display_name, role, ordinal, joined_at
+		FROM members
+		WHERE id = $2;
+	`
+
+	var member TeamMember
+	err := r.db.QueryRowContext(ctx, query, teamID, memberID).Scan(member.scanTargets()...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return TeamMember{}, ErrNotFound
+	}
+	if err != nil {
+		return TeamMember{}, fmt.Errorf("scan team member: %w", err)
+	}
+
+	return member, nil
+}
+
+// SetRole changes a member's role to admin or member. The owner's role cannot be changed.
+// Returns ErrNotFound if no such non-owner member exists.
+func (r *TeamRepository) SetRole(ctx context.Context, teamID, memberID, role string) error {
+	const query = `
+		UPDATE team_members
+		SET role = $3
+		WHERE team_id = $1 AND id = $2 AND role <> 'owner';
+	`
+
+	result, err := r.db.ExecContext(ctx, query, teamID, memberID, role)
+	if err != nil {
+		return fmt.Errorf("update team role: %w", err)
+	}
+
+	return requireAffected(result, "updated team role")
+}
+
+// RemoveMember removes a member on behalf of someone with actorRole: owners may remove admins
+// and members, admins only members. Returns ErrNotFound if no such removable member exists.
+func (r *TeamRepository) RemoveMember(ctx context.Context, teamID, memberID, actorRole string) error {
+	const query = `
+		DELETE FROM team_members
+		WHERE team_id = $1 AND id = $2
+		  AND (role = 'member' OR (role = 'admin' AND $3 = 'owner'));
+	`
+
+	result, err := r.db.ExecContext(ctx, query, teamID, memberID, actorRole)
+	if err != nil {
+		return fmt.Errorf("delete team member: %w", err)
+	}
+
+	return requireAffected(result, "deleted team member")
+}
+
+// RotateInviteCode replaces the team's invite code so the old one stops working.
+// Returns ErrConflict if the new code is already in use.
+func (r *TeamRepository) RotateInviteCode(ctx context.Context, teamID, inviteCode string) error {
+	result, err := r.db.ExecContext(ctx, `UPDATE teams SET invite_code = $2 WHERE id = $1;`, teamID, inviteCode)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return fmt.Errorf("update invite code: %w", err)
+	}
+
+	return requireAffected(result, "updated invite code")
+}
+
+// Delete removes a team and all its memberships.
+func (r *TeamRepository) Delete(ctx context.Context, teamID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM teams WHERE id = $1;`, teamID)
+	if err != nil {
+		return fmt.Errorf("delete team: %w", err)
+	}
+
+	return requireAffected(result, "deleted team")
+}
+
+// Dashboard aggregates every run of the team's members that completed in the period.
+// Members without runs are listed with zero totals.
+func (r *TeamRepository) Dashboard(ctx context.Context, teamID, period string) (TeamDashboard, error) {
+	since := periodStart(period, time.Now())
+	dashboard := TeamDashboard{Members: make([]TeamMemberStats, 0)}
+
+	totalsQuery := `
+		SELECT ` + statsColumns + `
+		FROM practice_history
+		WHERE user_id IN (SELECT user_id FROM team_members WHERE team_id = $1)
+		  AND completed_at >= $2;
+	`
+
+	if err := r.db.QueryRowContext(ctx, totalsQuery, teamID, since).Scan(dashboard.Totals.scanTargets()...); err != nil {
+		return TeamDashboard{}, fmt.Errorf("query team totals: %w", err)
+	}
+
+	// The lateral aggregate has no GROUP BY, so it yields one row even for members without runs.
+	membersQuery := teamMembersCTE + `
+		SELECT m.id, m.user_id, m.display_name, m.role, m.ordinal, m.joined_at, s.*
+		FROM members m
+		CROSS JOIN LATERAL (
+			SELECT ` + statsColumns + `
+			FROM practice_history h
+			WHERE h.user_id = m.user_id AND h.completed_at >= $2
+		) s
+		ORDER BY m.ordinal;
+	`
+
+	rows, err := r.db.QueryContext(ctx, membersQuery, teamID, since)
+	if err != nil {
+		return TeamDashboard{}, fmt.Errorf("query team member stats: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stats TeamMemberStats
+		if err := rows.Scan(append(stats.TeamMember.scanTargets(), stats.HistoryStats.scanTargets()...)...); err != nil {
+			return TeamDashboard{}, fmt.Errorf("scan team member stats: %w", err)
+		}
+
+		dashboard.Members = append(dashboard.Members, stats)
+	}
+
+	if err := rows.Err(); err != nil {
+		return TeamDashboard{}, fmt.Errorf("iterate team member stats: %w", err)
+	}
+
+	return dashboard, nil
+}
+
+// Leaderboard ranks the team's members by their best verified run in the period,
+// by WPM, then accuracy, then the earlier run. Members without a qualifying run are left out.
+func (r *TeamRepository) Leaderboard(ctx context.Context, q TeamLeaderboardQuery) ([]TeamLeaderboardEntry, error) {
+	query := teamMembersCTE + `
+		SELECT id, user_id, display_name, role, ordinal, joined_at,
+		       history_id, language, wpm, accuracy, completed_at
+		FROM (
+			SELECT DISTINCT ON (m.id)
+			       m.id, m.user_id, m.display_name, m.role, m.ordinal, m.joined_at,
+			       h.id AS history_id, h.language, h.wpm, h.accuracy, h.completed_at
+			FROM members m
+			JOIN practice_history h ON h.user_id = m.user_id
+			WHERE h.verification_status = 'verified'
+			  AND h.completed_at >= $2
+			  AND ($3 = '' OR h.language = $3)
+			ORDER BY m.id, h.wpm DESC, h.accuracy DESC, h.completed_at ASC
+		) best
+		ORDER BY wpm DESC, accuracy DESC, completed_at ASC, ordinal;
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, q.TeamID, periodStart(q.Period, time.Now()), q.Language)
+	if err != nil {
+		return nil, fmt.Errorf("query team leaderboard: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]TeamLeaderboardEntry, 0)
+	for rows.Next() {
+		entry := TeamLeaderboardEntry{Rank: len(entries) + 1}
+		if err := rows.Scan(append(entry.Member.scanTargets(),
+			&entry.HistoryID,
+			&entry.Language,
+			&entry.WPM,
+			&entry.Accuracy,
+			&entry.CompletedAt,
+		)...); err != nil {
+			return nil, fmt.Errorf("scan team leaderboard entry: %w", err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate team leaderboard: %w", err)
+	}
+
+	return entries, nil
+}
+
+// deleteTeamMembershipsByUser removes the user from every team, handing over or deleting the teams they own.
+func deleteTeamMembershipsByUser(ctx context.Context, tx dbtx, userID string) error {
+	rows, err := tx.QueryContext(ctx, `SELECT team_id FROM team_members WHERE user_id = $1;`, userID)
+	if err != nil {
+		return fmt.Errorf("query user teams: %w", err)
+	}
+
+	var teamIDs []string
+	for rows.Next() {
+		var teamID string
+		if err := rows.Scan(&teamID); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan user team: %w", err)
+		}
+
+		teamIDs = append(teamIDs, teamID)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate user teams: %w", err)
+	}
+
+	for _, teamID := range teamIDs {
+		if err := leaveTeam(ctx, tx, teamID, userID); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scanTargets returns pointers matching the member columns of teamMembersCTE.
+func (m *TeamMember) scanTargets() []any {
+	return []any{&m.ID, &m.UserID, &m.DisplayName, &m.Role, &m.Ordinal, &m.JoinedAt}
+}
+
+func scanTeamMembership(row rowScanner) (TeamMembership, error) {
+	var membership TeamMembership
+	err := row.Scan(
+		&membership.ID,
+		&membership.Name,
+		&membership.InviteCode,
+		&membership.CreatedAt,
+		&membership.Role,
+		&membership.MemberCount,
+	)
+
+	return membership, err
+}
+
+// periodStart returns the beginning of a leaderboard period at now, using the same UTC windows
+// as the leaderboard_entries view. All-time periods start at the zero time.
+func periodStart(period string, now time.Time) time.Time {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch period {
+	case LeaderboardDaily:
+		return day
+	case LeaderboardWeekly:
+		// Weeks start on Monday, like date_trunc('week', ...).
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	default:
+		return time.Time{}
+	}
+}
+
+// requireAffected returns ErrNotFound when a statement changed no rows.
+func requireAffected(result sql.Result, subject string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("read %s count: %w", subject, err)
+	}
+
+	if affected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
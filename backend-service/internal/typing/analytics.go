@@ -0,0 +1,91 @@
+package typing
+
+import "sort"
+
+// maxLatencyMillis caps the gap counted as typing latency; longer gaps are pauses, not slow keys.
+const maxLatencyMillis = 2000
+
+// KeyStat aggregates attempts at a single expected character.
+// LatencyMillis is the summed time to produce the key correctly over LatencySamples presses.
+type KeyStat struct {
+	Key            string
+	Presses        int
+	Errors         int
+	LatencyMillis  int
+	LatencySamples int
+}
+
+// BigramStat aggregates clean transitions between two consecutive characters.
+type BigramStat struct {
+	Bigram        string
+	Occurrences   int
+	LatencyMillis int
+}
+
+// Summarize derives per-key and per-bigram statistics from a keystroke log.
+// When the snippet is known, mistakes are attributed to the character that was expected
+// rather than the one typed; otherwise the typed character is used.
+// Results are sorted by key and bigram for deterministic storage.
+func Summarize(snippet string, keystrokes []Keystroke) ([]KeyStat, []BigramStat) {
+	expected := []rune(snippet)
+	keys := make(map[string]*KeyStat)
+	bigrams := make(map[string]*BigramStat)
+
+	position := 0
+	previousCorrect := ""
+	for i, k := range keystrokes {
+		key := k.Char
+		if position < len(expected) {
+			key = string(expected[position])
+		}
+
+		stat, ok := keys[key]
+		if !ok {
+			stat = &KeyStat{Key: key}
+			keys[key] = stat
+		}
+		stat.Presses++
+
+		if !k.Correct {
+			stat.Errors++
+			previousCorrect = ""
+			continue
+		}
+
+		if i > 0 {
+			if gap := k.OffsetMillis - keystrokes[i-1].OffsetMillis; gap <= maxLatencyMillis {
+				stat.LatencyMillis += gap
+				stat.LatencySamples++
+
+				// Only transitions between two correct presses describe the bigram itself.
+				if previousCorrect != "" {
+					pair := previousCorrect + key
+					bigram, ok := bigrams[pair]
+					if !ok {
+						bigram = &BigramStat{Bigram: pair}
+						bigrams[pair] = bigram
+					}
+					bigram.Occurrences++
+					bigram.LatencyMillis += gap
+				}
+			}
+		}
+
+		previousCorrect = key
+		position++
+	}
+
+	keyStats := make([]KeyStat, 0, len(keys))
+	for _, stat := range keys {
+		keyStats = append(keyStats, *stat)
+	}
+	sort.Slice(keyStats, func(i, j int) bool { return keyStats[i].Key < keyStats[j].Key })
+
+	bigramStats := make([]BigramStat, 0, len(bigrams))
+	for _, stat := range bigrams {
+		bigramStats = append(bigramStats, *stat)
+	}
+	sort.Slice(bigramStats, func(i, j int) bool { return bigramStats[i].Bigram < bigramStats[j].Bigram })
+
+	return keyStats, bigramStats
+}
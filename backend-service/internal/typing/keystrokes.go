@@ -0,0 +1,107 @@
+// Package typing contains the domain model of a typing run that is independent of HTTP and storage.
+package typing
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// Encoding identifies the on-disk format produced by Compress.
+const Encoding = "gzip+json/v1"
+
+// Bounds of an acceptable keystroke log: a few hours of continuous typing at most.
+const (
+	MaxKeystrokes            = 50000
+	MaxKeystrokeOffsetMillis = 4 * 60 * 60 * 1000
+)
+
+// Keystroke is a single key press recorded by the client.
+// OffsetMillis is measured from the start of the run with paused time excluded.
+// Correct reports whether the key matched the expected character; incorrect keys do not advance the cursor.
+type Keystroke struct {
+	Char         string
+	OffsetMillis int
+	Correct      bool
+}
+
+// encodedKeystroke is the compact JSON form used inside the compressed blob.
+type encodedKeystroke struct {
+	C  string `json:"c"`
+	T  int    `json:"t"`
+	OK bool   `json:"ok,omitempty"`
+}
+
+// ValidateKeystrokes checks that a keystroke log is bounded, uses single characters
+// and has non-decreasing offsets. The error message is meant for the client.
+func ValidateKeystrokes(keystrokes []Keystroke) error {
+	if len(keystrokes) > MaxKeystrokes {
+		return errors.New("too many keystrokes")
+	}
+
+	previous := 0
+	for _, k := range keystrokes {
+		if utf8.RuneCountInString(k.Char) != 1 {
+			return errors.New("each keystroke must contain exactly one character")
+		}
+
+		if k.OffsetMillis < previous || k.OffsetMillis > MaxKeystrokeOffsetMillis {
+			return errors.New("keystroke offsets must be non-decreasing and within the run")
+		}
+
+		previous = k.OffsetMillis
+	}
+
+	return nil
+}
+
+// Compress serializes keystrokes to gzip-compressed JSON.
+func Compress(keystrokes []Keystroke) ([]byte, error) {
+	encoded := make([]encodedKeystroke, len(keystrokes))
+	for i, k := range keystrokes {
+		encoded[i] = encodedKeystroke{C: k.Char, T: k.OffsetMillis, OK: k.Correct}
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(encoded); err != nil {
+		zw.Close()
+		return nil, fmt.Errorf("encode keystrokes: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("compress keystrokes: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decompress restores keystrokes produced by Compress.
+func Decompress(data []byte) ([]Keystroke, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("open compressed keystrokes: %w", err)
+	}
+	defer zr.Close()
+
+	raw, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("decompress keystrokes: %w", err)
+	}
+
+	var encoded []encodedKeystroke
+	if err := json.Unmarshal(raw, &encoded); err != nil {
+		return nil, fmt.Errorf("decode keystrokes: %w", err)
+	}
+
+	keystrokes := make([]Keystroke, len(encoded))
+	for i, k := range encoded {
+		keystrokes[i] = Keystroke{Char: k.C, OffsetMillis: k.T, Correct: k.OK}
+	}
+
+	return keystrokes, nil
+}
@@ -0,0 +1,27 @@
+package typing
+
+import "math"
+
+// CalculateWPM mirrors the frontend formula: five characters make a word.
+func CalculateWPM(chars, seconds int) int {
+	if seconds <= 0 {
+		return 0
+	}
+
+	words := float64(chars) / 5
+	minutes := float64(seconds) / 60
+
+	return int(math.Round(words / minutes))
+}
+
+// CalculateAccuracy mirrors the frontend formula: the share of characters typed without an error, in percent.
+func CalculateAccuracy(totalChars, errorCount int) int {
+	if totalChars == 0 {
+		return 100
+	}
+
+	correct := float64(totalChars - errorCount)
+	accuracy := correct / float64(totalChars) * 100
+
+	return max(0, int(math.Round(accuracy)))
+}
@@ -0,0 +1,25 @@
+package typing
+
+// TimelinePoint is the cursor position reached OffsetMillis after the start of a run.
+type TimelinePoint struct {
+	OffsetMillis int
+	Position     int
+}
+
+// Timeline reduces a keystroke log to the moments the cursor advanced,
+// which is all a client needs to animate a ghost of the run.
+func Timeline(keystrokes []Keystroke) []TimelinePoint {
+	points := make([]TimelinePoint, 0, len(keystrokes))
+
+	position := 0
+	for _, k := range keystrokes {
+		if !k.Correct {
+			continue
+		}
+
+		position++
+		points = append(points, TimelinePoint{OffsetMillis: k.OffsetMillis, Position: position})
+	}
+
+	return points
+}
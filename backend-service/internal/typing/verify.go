@@ -0,0 +1,248 @@
+package typing
+
+import (
+	"math"
+	"sort"
+)
+
+// Status is the outcome of server-side verification stored with a run.
+type Status string
+
+const (
+	// StatusUnverified means the run carried no data the server could check.
+	StatusUnverified Status = "unverified"
+	// StatusVerified means the keystroke log matches the snippet text known to the server
+	// and the submitted numbers match the server's recomputation.
+	StatusVerified Status = "verified"
+	// StatusFlagged means the run was accepted but looks suspicious.
+	StatusFlagged Status = "flagged"
+)
+
+// Limits configures plausibility checks.
+type Limits struct {
+	// MaxWPM is the highest speed accepted at all; faster runs are rejected.
+	MaxWPM int
+	// MinIntervalMillis is the shortest plausible gap between two key presses.
+	MinIntervalMillis int
+	// MaxFastIntervalRatio is the share of gaps below MinIntervalMillis tolerated before flagging.
+	MaxFastIntervalRatio float64
+	// WPMTolerance and AccuracyTolerance bound the difference between claimed and recomputed values.
+	WPMTolerance      int
+	AccuracyTolerance int
+	// ClockToleranceMillis bounds the difference between a server-timed run and its keystroke log's span.
+	ClockToleranceMillis int
+}
+
+// DefaultLimits are tuned for human typists: the fastest recorded bursts stay below 300 WPM,
+// and sustained inter-key gaps under 15 ms are only produced by scripts or pasting.
+var DefaultLimits = Limits{
+	MaxWPM:               300,
+	MinIntervalMillis:    15,
+	MaxFastIntervalRatio: 0.2,
+	WPMTolerance:         2,
+	AccuracyTolerance:    2,
+	ClockToleranceMillis: 3000,
+}
+
+// Run is a submitted result together with the evidence available to check it.
+// Snippet is the text that was typed, when known to the server; Keystrokes is the optional key log.
+// ServerTimed marks runs whose DurationSeconds the server measured itself, such as race results:
+// their keystroke log must then span about as long as the server's clock says the run took.
+type Run struct {
+	WPM             int
+	Accuracy        int
+	Errors          int
+	DurationSeconds int
+	ServerTimed     bool
+	Snippet         string
+	Keystrokes      []Keystroke
+}
+
+// Verdict is the result of Verify.
+// When Recomputed is true the WPM, Accuracy, Errors and DurationSeconds fields hold the server's numbers
+// and should be stored instead of the claimed ones.
+type Verdict struct {
+	Status          Status
+	Rejected        bool
+	Reasons         []string
+	Recomputed      bool
+	WPM             int
+	Accuracy        int
+	Errors          int
+	DurationSeconds int
+}
+
+// Verify recomputes a run from its keystroke log using the same formulas as the client
+// and applies plausibility checks. Physically impossible runs are rejected; inconsistent
+// or bot-like runs are flagged.
+func Verify(run Run, limits Limits) Verdict {
+	verdict := Verdict{Status: StatusUnverified}
+
+	if run.WPM > limits.MaxWPM {
+		verdict.reject("wpm exceeds the plausible maximum")
+		return verdict
+	}
+
+	snippet := []rune(run.Snippet)
+
+	if len(snippet) > 0 && len(run.Keystrokes) == 0 {
+		// Typed characters implied by the claimed speed cannot exceed the snippet length.
+		implied := float64(run.WPM) * 5 * float64(run.DurationSeconds) / 60
+		if implied > float64(len(snippet))*1.05+5 {
+			verdict.reject("run is shorter than the snippet allows at the claimed speed")
+			return verdict
+		}
+	}
+
+	if len(run.Keystrokes) == 0 {
+		return verdict
+	}
+
+	// Without the snippet text the log can only be checked against itself, so the numbers
+	// are recomputed but the run is not vouched for.
+	if len(snippet) > 0 {
+		verdict.Status = StatusVerified
+	}
+	verdict.Recomputed = true
+
+	position := 0
+	errorPositions := make(map[int]bool)
+	for _, k := range run.Keystrokes {
+		if len(snippet) > 0 {
+			if position >= len(snippet) {
+				verdict.flag("keystroke log continues past the end of the snippet")
+				break
+			}
+
+			if (string(snippet[position]) == k.Char) != k.Correct {
+				verdict.flag("keystroke log does not match the snippet")
+			}
+		}
+
+		if k.Correct {
+			position++
+		} else {
+			errorPositions[position] = true
+		}
+	}
+
+	last := run.Keystrokes[len(run.Keystrokes)-1]
+	verdict.DurationSeconds = last.OffsetMillis / 1000
+	verdict.Errors = len(errorPositions)
+	verdict.WPM = CalculateWPM(position, verdict.DurationSeconds)
+
+	// Against a known snippet, characters the log never reached count as missed, so a run that
+	// stops early cannot keep the accuracy of the part it did type.
+	total, missed := position, 0
+	if len(snippet) > 0 {
+		total, missed = len(snippet), len(snippet)-position
+		if missed > 0 && errorPositions[position] {
+			// The character the log stopped at is already counted as an error.
+			missed--
+		}
+	}
+	verdict.Accuracy = CalculateAccuracy(total, verdict.Errors+missed)
+
+	if position < len(snippet) {
+		verdict.flag("keystroke log does not reach the end of the snippet")
+	}
+
+	if verdict.WPM > limits.MaxWPM {
+		verdict.reject("recomputed wpm exceeds the plausible maximum")
+		return verdict
+	}
+
+	if ratio := fastIntervalRatio(run.Keystrokes, limits.MinIntervalMillis); ratio > limits.MaxFastIntervalRatio {
+		verdict.flag("too many inter-key intervals are shorter than humanly possible")
+	}
+
+	if median := medianInterval(run.Keystrokes); len(run.Keystrokes) > 1 && median < limits.MinIntervalMillis {
+		verdict.reject("inter-key intervals are shorter than humanly possible")
+		return verdict
+	}
+
+	if abs(run.WPM-verdict.WPM) > max(limits.WPMTolerance, int(math.Ceil(float64(verdict.WPM)*0.05))) {
+		verdict.flag("submitted wpm does not match the keystroke log")
+	}
+
+	if abs(run.Accuracy-verdict.Accuracy) > limits.AccuracyTolerance {
+		verdict.flag("submitted accuracy does not match the keystroke log")
+	}
+
+	if run.Errors != verdict.Errors {
+		verdict.flag("submitted errors do not match the keystroke log")
+	}
+
+	if run.ServerTimed {
+		span := last.OffsetMillis - run.Keystrokes[0].OffsetMillis
+		if abs(run.DurationSeconds*1000-span) > limits.ClockToleranceMillis {
+			verdict.flag("keystroke log does not span the time measured by the server")
+		}
+	} else if abs(run.DurationSeconds-verdict.DurationSeconds) > 1 {
+		verdict.flag("submitted time does not match the keystroke log")
+	}
+
+	return verdict
+}
+
+func (v *Verdict) reject(reason string) {
+	v.Rejected = true
+	v.Reasons = append(v.Reasons, reason)
+}
+
+func (v *Verdict) flag(reason string) {
+	v.Status = StatusFlagged
+	for _, existing := range v.Reasons {
+		if existing == reason {
+			return
+		}
+	}
+	v.Reasons = append(v.Reasons, reason)
+}
+
+// intervals returns the gaps in milliseconds between consecutive key presses.
+func intervals(keystrokes []Keystroke) []int {
+	if len(keystrokes) < 2 {
+		return nil
+	}
+
+	gaps := make([]int, len(keystrokes)-1)
+	for i := 1; i < len(keystrokes); i++ {
+		gaps[i-1] = keystrokes[i].OffsetMillis - keystrokes[i-1].OffsetMillis
+	}
+
+	return gaps
+}
+
+func fastIntervalRatio(keystrokes []Keystroke, threshold int) float64 {
+	gaps := intervals(keystrokes)
+	if len(gaps) == 0 {
+		return 0
+	}
+
+	fast := 0
+	for _, gap := range gaps {
+		if gap < threshold {
+			fast++
+		}
+	}
+
+	return float64(fast) / float64(len(gaps))
+}
+
+func medianInterval(keystrokes []Keystroke) int {
+	gaps := intervals(keystrokes)
+	if len(gaps) == 0 {
+		return 0
+	}
+
+	sort.Ints(gaps)
+	return gaps[len(gaps)/2]
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -7,6 +7,7 @@ import NotFound from "@/pages/NotFound";
 import Practice from "@/pages/Practice";
 import Verification from "@/pages/Verification";
 import Recovery from "@/pages/Recovery";
+import RestoreAccount from "@/pages/RestoreAccount";
 import Settings from "@/pages/Settings";
 import { ProtectedRoute } from "@/shared/components/ProtectedRoute";
 
@@ -17,6 +18,7 @@ export const AppRoutes = (): ReactElement => {
       <Route path="/auth" element={<Auth />} />
       <Route path="/verification" element={<Verification />} />
       <Route path="/recovery" element={<Recovery />} />
+      <Route path="/account/restore" element={<RestoreAccount />} />
       <Route
         path="/practice"
         element={
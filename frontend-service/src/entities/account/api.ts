@@ -1,5 +1,6 @@
 const API_BASE_URL = import.meta.env.VITE_API_BASE_URL || "http://localhost:4455";
 const ACCOUNT_ENDPOINT = `${API_BASE_URL}/api/private/account`;
+const RESTORE_ENDPOINT = `${API_BASE_URL}/api/public/account/restore`;
 
 interface ErrorResponse {
   error?: string;
@@ -19,7 +20,15 @@ const parseError = async (response: Response): Promise<string> => {
   return response.statusText || "Unexpected error";
 };
 
-export const deleteAccount = async (): Promise<void> => {
+export interface PendingDeletion {
+  status: "pending";
+  purge_after: string;
+  restore_token?: string;
+}
+
+// Resolves to null when the account is already gone, or to the pending deletion when the
+// account was deactivated and is purged later; restore_token can undo it until purge_after.
+export const deleteAccount = async (): Promise<PendingDeletion | null> => {
   const response = await fetch(ACCOUNT_ENDPOINT, {
     method: "DELETE",
     credentials: "include",
@@ -28,6 +37,27 @@ export const deleteAccount = async (): Promise<void> => {
     },
   });
 
+  if (response.status === 204) {
+    return null;
+  }
+
+  if (response.status === 202) {
+    return (await response.json()) as PendingDeletion;
+  }
+
+  throw new Error(await parseError(response));
+};
+
+export const restoreAccount = async (token: string): Promise<void> => {
+  const response = await fetch(RESTORE_ENDPOINT, {
+    method: "POST",
+    headers: {
+      Accept: "application/json",
+      "Content-Type": "application/json",
+    },
+    body: JSON.stringify({ token }),
+  });
+
   if (response.status === 204) {
     return;
   }
@@ -65,30 +65,56 @@ const getHistory = async (limit = 50): Promise<HistoryEntry[]> => {
   return data.map(parseHistoryEntry);
 };
 
+const MAX_SAVE_ATTEMPTS = 3;
+const RETRY_DELAY_MS = 500;
+
+const delay = (ms: number) => new Promise((resolve) => window.setTimeout(resolve, ms));
+
 const addHistoryEntry = async (entry: HistoryInput): Promise<HistoryEntry> => {
-  const response = await fetch(HISTORY_ENDPOINT, {
-    method: "POST",
-    credentials: "include",
-    headers: {
-      "Content-Type": "application/json",
-      Accept: "application/json",
-    },
-    body: JSON.stringify({
-      language: entry.language,
-      wpm: entry.wpm,
-      accuracy: entry.accuracy,
-      errors: entry.errors,
-      time: entry.time,
-      date: entry.date,
-    }),
+  // Every attempt carries the same key, so a retry after a lost response cannot record the run twice.
+  const idempotencyKey = crypto.randomUUID();
+  const body = JSON.stringify({
+    language: entry.language,
+    wpm: entry.wpm,
+    accuracy: entry.accuracy,
+    errors: entry.errors,
+    time: entry.time,
+    date: entry.date,
   });
 
-  if (!response.ok) {
-    throw new Error(await parseError(response));
-  }
+  for (let attempt = 1; ; attempt++) {
+    let response: Response;
+    try {
+      response = await fetch(HISTORY_ENDPOINT, {
+        method: "POST",
+        credentials: "include",
+        headers: {
+          "Content-Type": "application/json",
+          Accept: "application/json",
+          "Idempotency-Key": idempotencyKey,
+        },
+        body,
+      });
+    } catch (error) {
+      if (attempt >= MAX_SAVE_ATTEMPTS) {
+        throw error;
+      }
+      await delay(RETRY_DELAY_MS * attempt);
+      continue;
+    }
+
+    if (response.status >= 500 && attempt < MAX_SAVE_ATTEMPTS) {
+      await delay(RETRY_DELAY_MS * attempt);
+      continue;
+    }
+
+    if (!response.ok) {
+      throw new Error(await parseError(response));
+    }
 
-  const data = (await response.json()) as HistoryResponse;
-  return parseHistoryEntry(data);
+    const data = (await response.json()) as HistoryResponse;
+    return parseHistoryEntry(data);
+  }
 };
 
 const clearHistory = async (): Promise<void> => {
@@ -0,0 +1,117 @@
+import { useState } from "react";
+import { useNavigate, useSearchParams } from "react-router-dom";
+import { Code2, RotateCcw, UserCheck } from "lucide-react";
+import { Button } from "@/shared/ui/button";
+import { Input } from "@/shared/ui/input";
+import { Label } from "@/shared/ui/label";
+import { Card, CardContent, CardDescription, CardHeader, CardTitle } from "@/shared/ui/card";
+import { ThemeToggle } from "@/features/theme-toggle";
+import { toast } from "sonner";
+import { restoreAccount } from "@/entities/account/api";
+
+// Restores an account that is still in its deletion grace period. The token comes from the
+// restore link shown when the account was deleted; the page works without a session because
+// a deactivated account cannot sign in.
+const RestoreAccount = () => {
+  const navigate = useNavigate();
+  const [searchParams] = useSearchParams();
+  const [token, setToken] = useState(searchParams.get("token") ?? "");
+  const [isLoading, setIsLoading] = useState(false);
+  const [isRestored, setIsRestored] = useState(false);
+
+  const handleRestore = async (e: React.FormEvent) => {
+    e.preventDefault();
+
+    const trimmedToken = token.trim();
+    if (!trimmedToken) {
+      toast.error("Please enter your restore token");
+      return;
+    }
+
+    setIsLoading(true);
+    try {
+      await restoreAccount(trimmedToken);
+      setIsRestored(true);
+      toast.success("Account restored");
+    } catch (error) {
+      toast.error(error instanceof Error ? error.message : "Failed to restore account");
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  if (isRestored) {
+    return (
+      <div className="min-h-screen bg-background flex items-center justify-center p-4">
+        <div className="absolute top-4 right-4">
+          <ThemeToggle />
+        </div>
+
+        <Card className="w-full max-w-md">
+          <CardHeader className="text-center">
+            <div className="flex justify-center mb-4">
+              <UserCheck className="h-16 w-16 text-green-500" />
+            </div>
+            <CardTitle>Account Restored</CardTitle>
+            <CardDescription>
+              Your account is active again and will not be deleted. You can now login as before.
+            </CardDescription>
+          </CardHeader>
+          <CardContent className="space-y-4">
+            <Button onClick={() => navigate("/auth")} className="w-full">
+              Go to Login
+            </Button>
+          </CardContent>
+        </Card>
+      </div>
+    );
+  }
+
+  return (
+    <div className="min-h-screen bg-background flex items-center justify-center p-4">
+      <div className="absolute top-4 right-4">
+        <ThemeToggle />
+      </div>
+
+      <div className="w-full max-w-md space-y-6">
+        <div className="text-center space-y-2">
+          <div className="flex items-center justify-center gap-2 mb-4">
+            <Code2 className="h-10 w-10 text-primary" />
+            <h1 className="text-3xl font-bold">CodeType</h1>
+          </div>
+          <p className="text-muted-foreground">Restore your deleted account</p>
+        </div>
+
+        <Card>
+          <CardHeader>
+            <CardTitle className="text-center">Restore Account</CardTitle>
+            <CardDescription className="text-center">
+              Enter the restore token you received when you deleted your account. It works until the account is
+              permanently deleted.
+            </CardDescription>
+          </CardHeader>
+          <CardContent>
+            <form onSubmit={handleRestore} className="space-y-4">
+              <div className="space-y-2">
+                <Label htmlFor="restore-token">Restore Token</Label>
+                <Input
+                  id="restore-token"
+                  value={token}
+                  onChange={(e) => setToken(e.target.value)}
+                  autoComplete="off"
+                  disabled={isLoading}
+                />
+              </div>
+              <Button type="submit" className="w-full" disabled={isLoading}>
+                <RotateCcw className="h-4 w-4 mr-2" />
+                {isLoading ? "Restoring..." : "Restore account"}
+              </Button>
+            </form>
+          </CardContent>
+        </Card>
+      </div>
+    </div>
+  );
+};
+
+export default RestoreAccount;
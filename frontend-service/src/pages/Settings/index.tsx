@@ -21,7 +21,7 @@ import {
 import { ThemeToggle } from "@/features/theme-toggle";
 import { toast } from "sonner";
 import { useAuth } from "@/shared/hooks/use-auth";
-import { deleteAccount as deleteAccountRequest } from "@/entities/account/api";
+import { deleteAccount as deleteAccountRequest, type PendingDeletion } from "@/entities/account/api";
 import {
   initiateSettingsFlow,
   getSettingsFlow,
@@ -38,6 +38,8 @@ const Settings = () => {
   const [isInitializing, setIsInitializing] = useState(true);
   const [isLoading, setIsLoading] = useState(false);
   const [isDeletingAccount, setIsDeletingAccount] = useState(false);
+  // Set after a deletion that can still be restored; its token is shown once before signing out.
+  const [pendingDeletion, setPendingDeletion] = useState<PendingDeletion | null>(null);
 
   // Profile form fields.
   const [email, setEmail] = useState("");
@@ -245,8 +247,13 @@ const Settings = () => {
     }
     setIsDeletingAccount(true);
     try {
-      await deleteAccountRequest();
-      toast.success("Account deleted");
+      const pending = await deleteAccountRequest();
+      if (pending?.restore_token) {
+        // The token cannot be fetched again, so sign out only after the user has seen it.
+        setPendingDeletion(pending);
+        return;
+      }
+      toast.success(pending ? "Account deactivated" : "Account deleted");
       await logout();
     } catch (error) {
       toast.error(error instanceof Error ? error.message : "Failed to delete account");
@@ -255,6 +262,24 @@ const Settings = () => {
     }
   };
 
+  const restoreLink = pendingDeletion?.restore_token
+    ? `${window.location.origin}/account/restore?token=${encodeURIComponent(pendingDeletion.restore_token)}`
+    : "";
+
+  const handleCopyRestoreLink = async () => {
+    try {
+      await navigator.clipboard.writeText(restoreLink);
+      toast.success("Restore link copied");
+    } catch {
+      toast.error("Failed to copy the link, please copy it manually");
+    }
+  };
+
+  const handleFinishDeletion = async () => {
+    setPendingDeletion(null);
+    await logout();
+  };
+
   if (isInitializing) {
     return (
       <div className="min-h-screen bg-background flex items-center justify-center">
@@ -482,7 +507,8 @@ const Settings = () => {
                 Delete Account
               </CardTitle>
               <CardDescription>
-                Permanently remove your account, sessions, and typing history. This action cannot be undone.
+                Deactivate your account now and permanently remove it, your sessions, and typing history once the
+                grace period ends.
               </CardDescription>
             </CardHeader>
             <CardContent className="flex flex-col gap-4 sm:flex-row sm:items-center sm:justify-between">
@@ -499,8 +525,8 @@ const Settings = () => {
                   <AlertDialogHeader>
                     <AlertDialogTitle>Delete account?</AlertDialogTitle>
                     <AlertDialogDescription>
-                      This will permanently remove your account, active sessions, and stored practice history. You will
-                      need to register again to use the platform.
+                      Your account is deactivated right away and can be restored until the grace period ends. After
+                      that your account, active sessions, and stored practice history are removed permanently.
                     </AlertDialogDescription>
                   </AlertDialogHeader>
                   <AlertDialogFooter>
@@ -519,6 +545,40 @@ const Settings = () => {
               </AlertDialog>
             </CardContent>
           </Card>
+
+          <AlertDialog open={pendingDeletion !== null}>
+            <AlertDialogContent>
+              <AlertDialogHeader>
+                <AlertDialogTitle>Account deactivated</AlertDialogTitle>
+                <AlertDialogDescription>
+                  Your account will be permanently deleted on{" "}
+                  {pendingDeletion ? new Date(pendingDeletion.purge_after).toLocaleString() : ""}. Until then you can
+                  restore it with the link below. Save it now: it is shown only once and cannot be sent again.
+                </AlertDialogDescription>
+              </AlertDialogHeader>
+              <div className="space-y-2">
+                <Label htmlFor="restore-link">Restore link</Label>
+                <Input id="restore-link" value={restoreLink} readOnly onFocus={(e) => e.target.select()} />
+              </div>
+              <AlertDialogFooter>
+                <Button
+                  variant="outline"
+                  onClick={() => {
+                    void handleCopyRestoreLink();
+                  }}
+                >
+                  Copy link
+                </Button>
+                <AlertDialogAction
+                  onClick={() => {
+                    void handleFinishDeletion();
+                  }}
+                >
+                  I saved it, sign out
+                </AlertDialogAction>
+              </AlertDialogFooter>
+            </AlertDialogContent>
+          </AlertDialog>
         </div>
       </div>
     </div>